@@ -1,28 +1,33 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
+	"goNews/pkg/config"
 	"goNews/pkg/db"
+	"goNews/pkg/supervisor"
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
+	"time"
 )
 
 type API struct {
-	r  *mux.Router
-	db *db.DB
+	r    *mux.Router
+	db   db.Store
+	conf *config.Config
 }
 
-func New(db *db.DB, errChan chan<- error) *API {
+func New(db db.Store, conf *config.Config, errChan chan<- error) *API {
 	if db == nil {
 		errChan <- fmt.Errorf("database instance is nil")
 		return nil
 	}
 
-	api := &API{db: db, r: mux.NewRouter()}
+	api := &API{db: db, conf: conf, r: mux.NewRouter()}
 	api.endpoints(errChan)
 	return api
 }
@@ -31,10 +36,51 @@ func (api *API) Router() *mux.Router {
 	return api.r
 }
 
+// Serve обслуживает HTTP-запросы на адресе из настроек до отмены контекста.
+// После отмены новые соединения не принимаются, а начатые запросы
+// дорабатывают не дольше shutdown_timeout. Ошибка открытия адреса (занят,
+// нет прав) неустранима: перезапуск её не исправит.
+func (api *API) Serve(ctx context.Context) error {
+	ln, err := net.Listen("tcp", api.conf.Listen)
+	if err != nil {
+		return supervisor.Fatal(err)
+	}
+	srv := &http.Server{Addr: api.conf.Listen, Handler: api.r}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(api.conf.ShutdownTimeout)*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			srv.Close()
+			return fmt.Errorf("HTTP server shutdown: %w", err)
+		}
+		return nil
+	}
+}
+
 func (api *API) endpoints(errCn chan<- error) {
 	api.r.HandleFunc("/news/{col}", api.ordersHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/api/news", api.newsHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/api/search", api.searchHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/api/feeds", api.feedsHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/api/feeds", api.createFeedHandler).Methods(http.MethodPost)
+	api.r.HandleFunc("/api/feeds/{id}", api.feedHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/api/feeds/{id}", api.updateFeedHandler).Methods(http.MethodPatch)
+	api.r.HandleFunc("/api/feeds/{id}", api.deleteFeedHandler).Methods(http.MethodDelete)
+	api.r.HandleFunc("/api/opml", api.exportOPMLHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/api/opml", api.importOPMLHandler).Methods(http.MethodPost)
+	api.r.HandleFunc("/feed.rss", api.rssHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/feed.atom", api.atomHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/feed.json", api.jsonFeedHandler).Methods(http.MethodGet)
 
-	webappPath := filepath.Join(".", "src", "webapp")
+	webappPath := api.conf.WebappDir
 	if _, err := os.Stat(webappPath); os.IsNotExist(err) {
 		errCn <- fmt.Errorf("static files directory not found: %s", webappPath)
 		return
@@ -64,14 +110,15 @@ func (api *API) ordersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	news, err := api.db.News(r.Context(), col)
+	// Оставлен для совместимости: первая страница /api/news без конверта
+	page, err := api.db.News(r.Context(), db.NewsQuery{Limit: min(col, maxPageSize)})
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to fetch news: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(news); err != nil {
+	if err := json.NewEncoder(w).Encode(page.News); err != nil {
 		http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
 	}
 }
@@ -4,73 +4,45 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/gorilla/mux"
+	"goNews/pkg/config"
 	"goNews/pkg/db"
+	"goNews/pkg/db/memory"
+	"goNews/pkg/opml"
+	"goNews/pkg/rss/parser"
+	"goNews/pkg/supervisor"
 )
 
-// TestMain подготавливает тестовую базу данных и запускает тесты
-func TestMain(m *testing.M) {
-	ctx := context.Background()
-	pwd := os.Getenv("dbpass")
-	connStr := "postgres://postgres:" + pwd + "@localhost:5432/GoNews?sslmode=disable"
-
-	// Подключаемся к базе данных
-	pool, err := pgxpool.Connect(ctx, connStr)
+// setupTestDB возвращает хранилище в памяти с пятью тестовыми записями
+func setupTestDB(t *testing.T) db.Store {
+	store := memory.New()
+	feed, err := store.AddFeed(context.Background(), "http://example.com/feed")
 	if err != nil {
-		fmt.Printf("Failed to connect to database: %v\n", err)
-		os.Exit(1)
+		t.Fatalf("Failed to add test feed: %v", err)
 	}
-	defer pool.Close()
-
-	// Очищаем таблицу news перед тестами
-	_, err = pool.Exec(ctx, "TRUNCATE TABLE news RESTART IDENTITY;")
-	if err != nil {
-		fmt.Printf("Failed to truncate news table: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Вставляем тестовые данные
-	_, err = pool.Exec(ctx, `
-		INSERT INTO news (name, description, publication_date, link)
-		VALUES 
-			('Test News 1', 'Description 1', '2023-01-01', 'http://example.com/1'),
-			('Test News 2', 'Description 2', '2023-01-02', 'http://example.com/2'),
-			('Test News 3', 'Description 3', '2023-01-03', 'http://example.com/3'),
-			('Test News 4', 'Description 4', '2023-01-04', 'http://example.com/4'),
-			('Test News 5', 'Description 5', '2023-01-05', 'http://example.com/5')
-		ON CONFLICT (name) DO NOTHING;
-	`)
-	if err != nil {
-		fmt.Printf("Failed to insert test data: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Запускаем тесты
-	code := m.Run()
-
-	// Очищаем таблицу после тестов
-	_, err = pool.Exec(ctx, "TRUNCATE TABLE news RESTART IDENTITY;")
-	if err != nil {
-		fmt.Printf("Failed to clean up news table: %v\n", err)
+	news := make([]db.News, 0, 5)
+	for i := 1; i <= 5; i++ {
+		news = append(news, db.News{
+			FeedID:          feed.ID,
+			Name:            fmt.Sprintf("Test News %d", i),
+			Description:     fmt.Sprintf("Description %d", i),
+			PublicationDate: time.Date(2023, 1, i, 0, 0, 0, 0, time.UTC),
+			Link:            fmt.Sprintf("http://example.com/%d", i),
+			GUID:            fmt.Sprintf("guid-%d", i),
+		})
 	}
-
-	os.Exit(code)
-}
-
-// setupTestDB создает тестовую базу данных и возвращает объект DB
-func setupTestDB(t *testing.T) *db.DB {
-	ctx := context.Background()
-	errChan := make(chan error, 1)
-	dbInstance := db.New(ctx, errChan)
-	if dbInstance == nil {
-		t.Fatalf("Failed to initialize database: %v", <-errChan)
+	if err := store.StoreNews(context.Background(), news); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
 	}
-	return dbInstance
+	return store
 }
 
 // TestOrdersHandler проверяет эндпоинт /news/{col}
@@ -79,7 +51,7 @@ func TestOrdersHandler(t *testing.T) {
 	defer dbInstance.Close()
 
 	errChan := make(chan error, 1)
-	api := New(dbInstance, errChan)
+	api := New(dbInstance, config.Default(), errChan)
 	router := api.Router()
 
 	tests := []struct {
@@ -161,3 +133,485 @@ func TestOrdersHandler(t *testing.T) {
 	default:
 	}
 }
+
+// TestNewsHandler проверяет листание /api/news по курсорам и заголовку Link
+func TestNewsHandler(t *testing.T) {
+	dbInstance := setupTestDB(t)
+	defer dbInstance.Close()
+
+	errChan := make(chan error, 1)
+	router := New(dbInstance, config.Default(), errChan).Router()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr
+	}
+
+	// Идём по ссылкам rel="next" до конца, затем одну страницу назад
+	pages := [][]string{{"guid-5", "guid-4"}, {"guid-3", "guid-2"}, {"guid-1"}}
+	target := "/api/news?limit=2"
+	var prev string
+	for i, expected := range pages {
+		rr := get(target)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Page %d: status %d, body: %s", i, rr.Code, rr.Body.String())
+		}
+		var page newsPage
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatalf("Page %d: failed to decode response: %v", i, err)
+		}
+		var got []string
+		for _, n := range page.News {
+			got = append(got, n.GUID)
+		}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Fatalf("Page %d: got %v want %v", i, got, expected)
+		}
+
+		if (i == len(pages)-1) != (page.Next == "") || (i == 0) != (page.Prev == "") {
+			t.Fatalf("Page %d: unexpected cursors next %q, prev %q", i, page.Next, page.Prev)
+		}
+		link := rr.Header().Get("Link")
+		target = "/api/news?after=" + page.Next + "&limit=2"
+		if page.Next != "" && !strings.Contains(link, "<"+target+`>; rel="next"`) {
+			t.Errorf("Page %d: Link %q does not point to %s", i, link, target)
+		}
+		if page.Prev != "" && !strings.Contains(link, `rel="prev"`) {
+			t.Errorf("Page %d: Link %q has no previous page", i, link)
+		}
+		prev = page.Prev
+	}
+
+	rr := get("/api/news?limit=2&before=" + prev)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"guid-3"`) || !strings.Contains(rr.Body.String(), `"guid-2"`) {
+		t.Errorf("Unexpected previous page: %d %s", rr.Code, rr.Body.String())
+	}
+
+	for _, target := range []string{
+		"/api/news?after=garbage",
+		"/api/news?after=" + prev + "&before=" + prev,
+		"/api/news?limit=0",
+		"/api/news?limit=101",
+	} {
+		if rr := get(target); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, rr.Code)
+		}
+	}
+}
+
+// TestNewsFilters проверяет фильтры списка записей и ответы на неверные значения
+func TestNewsFilters(t *testing.T) {
+	dbInstance := setupTestDB(t)
+	defer dbInstance.Close()
+
+	errChan := make(chan error, 1)
+	router := New(dbInstance, config.Default(), errChan).Router()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedGUIDs  []string
+	}{
+		{name: "Date range", query: "since=2023-01-02&until=2023-01-04", expectedStatus: http.StatusOK, expectedGUIDs: []string{"guid-3", "guid-2"}},
+		{name: "RFC 3339", query: "since=2023-01-04T00:00:00Z", expectedStatus: http.StatusOK, expectedGUIDs: []string{"guid-5", "guid-4"}},
+		{name: "Relative", query: "since=7d", expectedStatus: http.StatusOK},
+		{name: "Include", query: "include=description+3", expectedStatus: http.StatusOK, expectedGUIDs: []string{"guid-3"}},
+		{name: "Exclude", query: "exclude=4&exclude=5&since=2023-01-03", expectedStatus: http.StatusOK, expectedGUIDs: []string{"guid-3"}},
+		{name: "Unknown feed", query: "feed=2,3&feed=4", expectedStatus: http.StatusOK},
+		{name: "Unknown author", query: "author=nobody", expectedStatus: http.StatusOK},
+		{name: "Invalid feed", query: "feed=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid since", query: "since=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "Negative duration", query: "until=-2h", expectedStatus: http.StatusBadRequest},
+		{name: "Empty range", query: "since=2023-01-03&until=2023-01-02", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/news?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			var page newsPage
+			if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var got []string
+			for _, n := range page.News {
+				got = append(got, n.GUID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expectedGUIDs, ",") {
+				t.Errorf("Got %v want %v", got, tt.expectedGUIDs)
+			}
+		})
+	}
+}
+
+// TestFeedsHandlers проверяет эндпоинты управления лентами /api/feeds
+func TestFeedsHandlers(t *testing.T) {
+	dbInstance := setupTestDB(t)
+	defer dbInstance.Close()
+
+	feedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>Not a feed</body></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel><title>Test Feed</title><link>http://example.com/</link></channel></rss>`))
+	}))
+	defer feedSrv.Close()
+
+	errChan := make(chan error, 1)
+	api := New(dbInstance, config.Default(), errChan)
+	router := api.Router()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodPost, "/api/feeds", fmt.Sprintf(`{"url": "%s/rss"}`, feedSrv.URL))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 on create, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created db.Feed
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode feed: %v", err)
+	}
+	if created.Title != "Test Feed" || created.SiteLink != "http://example.com/" || !created.Enabled {
+		t.Errorf("Unexpected created feed: %+v", created)
+	}
+	feedPath := fmt.Sprintf("/api/feeds/%d", created.ID)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Duplicate feed", method: http.MethodPost, path: "/api/feeds", body: fmt.Sprintf(`{"url": "%s/rss"}`, feedSrv.URL), expectedStatus: http.StatusConflict},
+		{name: "Not a feed", method: http.MethodPost, path: "/api/feeds", body: fmt.Sprintf(`{"url": "%s/page"}`, feedSrv.URL), expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Relative url", method: http.MethodPost, path: "/api/feeds", body: `{"url": "/rss"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid body", method: http.MethodPost, path: "/api/feeds", body: `{`, expectedStatus: http.StatusBadRequest},
+		{name: "List feeds", method: http.MethodGet, path: "/api/feeds", expectedStatus: http.StatusOK},
+		{name: "Get feed", method: http.MethodGet, path: feedPath, expectedStatus: http.StatusOK},
+		{name: "Rename feed", method: http.MethodPatch, path: feedPath, body: `{"title": "Renamed"}`, expectedStatus: http.StatusOK},
+		{name: "Pause feed", method: http.MethodPatch, path: feedPath, body: `{"enabled": false}`, expectedStatus: http.StatusOK},
+		{name: "Empty patch", method: http.MethodPatch, path: feedPath, body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "Unknown feed", method: http.MethodPatch, path: "/api/feeds/100000", body: `{"enabled": true}`, expectedStatus: http.StatusNotFound},
+		{name: "Delete feed", method: http.MethodDelete, path: feedPath, expectedStatus: http.StatusNoContent},
+		{name: "Delete again", method: http.MethodDelete, path: feedPath, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := do(tt.method, tt.path, tt.body)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.name == "Pause feed" {
+				var feed db.Feed
+				if err := json.NewDecoder(rr.Body).Decode(&feed); err != nil {
+					t.Fatalf("Failed to decode feed: %v", err)
+				}
+				if feed.Title != "Renamed" || feed.Enabled {
+					t.Errorf("Expected renamed paused feed, got %+v", feed)
+				}
+			}
+		})
+	}
+}
+
+// TestSearchHandler проверяет эндпоинт /api/search
+func TestSearchHandler(t *testing.T) {
+	dbInstance := setupTestDB(t)
+	defer dbInstance.Close()
+
+	errChan := make(chan error, 1)
+	router := New(dbInstance, config.Default(), errChan).Router()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedLength int
+	}{
+		{name: "All matching news", query: "q=news", expectedStatus: http.StatusOK, expectedLength: 5},
+		{name: "Limited", query: "q=news&limit=2", expectedStatus: http.StatusOK, expectedLength: 2},
+		{name: "Single match", query: "q=description+3", expectedStatus: http.StatusOK, expectedLength: 1},
+		{name: "No matches", query: "q=missing", expectedStatus: http.StatusOK, expectedLength: 0},
+		{name: "Missing query", query: "q=+", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid limit", query: "q=news&limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "Limit too large", query: "q=news&limit=1000", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/search?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var found []db.SearchResult
+			if err := json.NewDecoder(rr.Body).Decode(&found); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(found) != tt.expectedLength {
+				t.Errorf("Handler returned wrong number of results: got %d want %d", len(found), tt.expectedLength)
+			}
+			for _, f := range found {
+				if f.Name == "" || f.Snippet == "" {
+					t.Errorf("Expected news with snippet, got %+v", f)
+				}
+			}
+		})
+	}
+}
+
+// TestSyndication проверяет, что ленты /feed.* разбираются парсером
+// поллера и содержат одни и те же записи
+func TestSyndication(t *testing.T) {
+	dbInstance := setupTestDB(t)
+	defer dbInstance.Close()
+
+	errChan := make(chan error, 1)
+	router := New(dbInstance, config.Default(), errChan).Router()
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedType   string
+		expectedFormat string
+		expectedTitles []string
+	}{
+		{name: "RSS", target: "/feed.rss?limit=2", expectedStatus: http.StatusOK, expectedType: "application/rss+xml", expectedFormat: parser.FormatRSS, expectedTitles: []string{"Test News 5", "Test News 4"}},
+		{name: "Atom", target: "/feed.atom?limit=2", expectedStatus: http.StatusOK, expectedType: "application/atom+xml", expectedFormat: parser.FormatAtom, expectedTitles: []string{"Test News 5", "Test News 4"}},
+		{name: "JSON Feed", target: "/feed.json?limit=2", expectedStatus: http.StatusOK, expectedType: "application/feed+json", expectedFormat: parser.FormatJSON, expectedTitles: []string{"Test News 5", "Test News 4"}},
+		{name: "Search query", target: "/feed.rss?q=description+3", expectedStatus: http.StatusOK, expectedType: "application/rss+xml", expectedFormat: parser.FormatRSS, expectedTitles: []string{"Test News 3"}},
+		{name: "Empty", target: "/feed.atom?feed=42", expectedStatus: http.StatusOK, expectedType: "application/atom+xml", expectedFormat: parser.FormatAtom},
+		{name: "Invalid filter", target: "/feed.json?since=yesterday", expectedStatus: http.StatusBadRequest},
+	}
+
+	guids := make(map[string]string)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			contentType := rr.Header().Get("Content-Type")
+			if !strings.HasPrefix(contentType, tt.expectedType) {
+				t.Errorf("Content-Type = %q, expected %s", contentType, tt.expectedType)
+			}
+			if !strings.Contains(rr.Body.String(), "http://example.com"+tt.target) {
+				t.Errorf("No self link to %s in %s", tt.target, rr.Body.String())
+			}
+
+			feed, err := parser.ParseWithType(rr.Body, contentType)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if feed.Format != tt.expectedFormat || len(feed.Errors) > 0 {
+				t.Fatalf("Parsed format %q with errors %v", feed.Format, feed.Errors)
+			}
+			var titles []string
+			for _, item := range feed.Items {
+				titles = append(titles, item.Title)
+				if item.GUID == "" || item.Published.IsZero() || item.Link == "" {
+					t.Errorf("Item without guid, date or link: %+v", item)
+				}
+				// Идентификатор записи не зависит от формата
+				if guid, ok := guids[item.Title]; ok && guid != item.GUID {
+					t.Errorf("%s: guid %q, in another format %q", item.Title, item.GUID, guid)
+				}
+				guids[item.Title] = item.GUID
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.expectedTitles, ",") {
+				t.Errorf("Got %v want %v", titles, tt.expectedTitles)
+			}
+		})
+	}
+}
+
+// TestEntryID проверяет, какие GUID источника идут в ленты как есть
+func TestEntryID(t *testing.T) {
+	tests := []struct {
+		guid string
+		kept bool
+	}{
+		{guid: "https://habr.com/ru/articles/1/", kept: true},
+		{guid: "urn:uuid:1b4e28ba-2fa1-11d2-883f-0016d3cca427", kept: true},
+		{guid: "tag:example.com,2024:post-1", kept: true},
+		{guid: "Habr: новости недели"},
+		{guid: "mailto:news@example.com"},
+		{guid: "http://example.com/a b"},
+		{guid: "12345"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.guid, func(t *testing.T) {
+			id := entryID(db.News{FeedID: 1, GUID: tt.guid})
+			if kept := id == tt.guid; kept != tt.kept {
+				t.Errorf("entryID(%q) = %q, expected kept %v", tt.guid, id, tt.kept)
+			}
+			if !tt.kept && !strings.HasPrefix(id, "urn:uuid:") {
+				t.Errorf("entryID(%q) = %q, expected urn:uuid", tt.guid, id)
+			}
+		})
+	}
+}
+
+// TestOPMLHandlers проверяет импорт и экспорт подписок через /api/opml
+func TestOPMLHandlers(t *testing.T) {
+	dbInstance := setupTestDB(t)
+	defer dbInstance.Close()
+
+	errChan := make(chan error, 1)
+	router := New(dbInstance, config.Default(), errChan).Router()
+
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+		<opml version="2.0"><body>
+			<outline text="Tech"><outline text="Go blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom"/></outline>
+			<outline text="Example" type="rss" xmlUrl="http://example.com/rss"/>
+		</body></opml>`
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedAdded    int
+		expectedExisting int
+	}{
+		{name: "First import", body: doc, expectedStatus: http.StatusOK, expectedAdded: 2},
+		{name: "Repeated import", body: doc, expectedStatus: http.StatusOK, expectedExisting: 2},
+		{name: "Not OPML", body: `{"url": "http://example.com/rss"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/opml", strings.NewReader(tt.body)))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			var result struct {
+				Added    []string `json:"added"`
+				Existing []string `json:"existing"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(result.Added) != tt.expectedAdded || len(result.Existing) != tt.expectedExisting {
+				t.Errorf("Unexpected import result: %+v", result)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/opml", nil))
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/x-opml") {
+		t.Fatalf("Unexpected export response: %d %v", rr.Code, rr.Header())
+	}
+	subs, err := opml.Parse(rr.Body)
+	if err != nil {
+		t.Fatalf("Exported document does not parse: %v", err)
+	}
+	if len(subs) != 3 || subs[1].Category != "Tech" || subs[2].URL != "http://example.com/rss" {
+		t.Errorf("Unexpected exported subscriptions: %+v", subs)
+	}
+}
+
+// TestServeShutdown проверяет, что при остановке сервер дорабатывает начатый запрос
+func TestServeShutdown(t *testing.T) {
+	started := make(chan struct{})
+	api := &API{r: mux.NewRouter(), conf: config.Default()}
+	api.r.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	api.conf.Listen = addr
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- api.Serve(ctx)
+	}()
+
+	// Останавливаем сервер, пока запрос ещё обрабатывается
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = http.Get("http://" + addr + "/slow"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || string(body) != "done" {
+		t.Errorf("Expected completed response, got status %d body %q err %v", resp.StatusCode, body, err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
+
+// TestServeAddressInUse проверяет, что занятый адрес - неустранимая ошибка
+func TestServeAddressInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve port: %v", err)
+	}
+	defer ln.Close()
+
+	api := &API{r: mux.NewRouter(), conf: config.Default()}
+	api.conf.Listen = ln.Addr().String()
+	err = api.Serve(context.Background())
+	if err == nil || !supervisor.IsFatal(err) {
+		t.Errorf("Serve() error = %v, expected a fatal error", err)
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"goNews/pkg/db"
+	"goNews/pkg/rss"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// feedRequest - тело POST /api/feeds.
+type feedRequest struct {
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	Enabled  *bool  `json:"enabled"`
+	Category string `json:"category"`
+}
+
+func (api *API) feedsHandler(w http.ResponseWriter, r *http.Request) {
+	feeds, err := api.db.Feeds(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to fetch feeds: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, feeds)
+}
+
+func (api *API) feedHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := feedID(w, r)
+	if !ok {
+		return
+	}
+	feed, err := api.db.Feed(r.Context(), id)
+	if err != nil {
+		writeFeedError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, feed)
+}
+
+// createFeedHandler добавляет ленту, предварительно скачав её один раз:
+// адреса, по которым не отдаётся лента, отклоняются.
+func (api *API) createFeedHandler(w http.ResponseWriter, r *http.Request) {
+	var req feedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "url must be an absolute http(s) address", http.StatusBadRequest)
+		return
+	}
+
+	parsed, err := rss.Probe(r.Context(), req.URL)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("not a valid feed: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	feed := db.Feed{
+		URL:         req.URL,
+		Title:       parsed.Title,
+		SiteLink:    parsed.Link,
+		Description: parsed.Description,
+		Icon:        parsed.Image,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		Category:    req.Category,
+	}
+	feed, err = api.db.CreateFeed(r.Context(), feed)
+	if err != nil {
+		writeFeedError(w, err)
+		return
+	}
+	if req.Title != "" {
+		feed, err = api.db.UpdateFeed(r.Context(), feed.ID, db.FeedPatch{Title: &req.Title})
+		if err != nil {
+			writeFeedError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/feeds/%d", feed.ID))
+	writeJSON(w, http.StatusCreated, feed)
+}
+
+// updateFeedHandler переименовывает ленту, ставит её на паузу, меняет
+// интервал опроса или папку.
+func (api *API) updateFeedHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := feedID(w, r)
+	if !ok {
+		return
+	}
+	var patch db.FeedPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if patch.FetchInterval != nil && *patch.FetchInterval < 0 {
+		http.Error(w, "fetch_interval must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	feed, err := api.db.UpdateFeed(r.Context(), id, patch)
+	if err != nil {
+		writeFeedError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, feed)
+}
+
+func (api *API) deleteFeedHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := feedID(w, r)
+	if !ok {
+		return
+	}
+	if err := api.db.DeleteFeed(r.Context(), id); err != nil {
+		writeFeedError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func feedID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid feed id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeFeedError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		http.Error(w, "feed not found", http.StatusNotFound)
+	case errors.Is(err, db.ErrFeedExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, db.ErrNoFeedPatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("failed to encode response: %v\n", err)
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"fmt"
+	"goNews/pkg/db"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Размер страницы по умолчанию и наибольший допустимый, для списка
+// записей и поиска.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// maxFilterValues - сколько лент или слов можно перечислить в одном фильтре.
+const maxFilterValues = 50
+
+// newsPage - ответ GET /api/news. Курсоры next и prev передаются в
+// параметрах after и before следующего запроса.
+type newsPage struct {
+	News []db.News `json:"news"`
+	Next string    `json:"next,omitempty"`
+	Prev string    `json:"prev,omitempty"`
+}
+
+// newsHandler обслуживает GET /api/news?limit=&after=&before=: страницу
+// записей, новые первыми, с фильтрами из parseFilters. Соседние страницы
+// указываются и в заголовке Link.
+func (api *API) newsHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit, ok := pageSize(w, params)
+	if !ok {
+		return
+	}
+	if params.Get("after") != "" && params.Get("before") != "" {
+		http.Error(w, "after and before are mutually exclusive", http.StatusBadRequest)
+		return
+	}
+	q, err := parseFilters(params, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Limit = limit
+	if q.After, ok = cursorParam(w, params, "after"); !ok {
+		return
+	}
+	if q.Before, ok = cursorParam(w, params, "before"); !ok {
+		return
+	}
+
+	page, err := api.db.News(r.Context(), q)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to fetch news: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := newsPage{News: page.News}
+	var links []string
+	if page.Next != nil {
+		resp.Next = page.Next.String()
+		links = append(links, pageLink(r, "after", resp.Next, "next"))
+	}
+	if page.Prev != nil {
+		resp.Prev = page.Prev.String()
+		links = append(links, pageLink(r, "before", resp.Prev, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseFilters читает фильтры списка: feed - id лент (параметр повторяется
+// или перечисляет id через запятую), since и until - границы даты
+// публикации, include и exclude - слова или фразы (параметры повторяются),
+// author - автор записи.
+func parseFilters(params url.Values, now time.Time) (db.NewsQuery, error) {
+	var q db.NewsQuery
+	for _, value := range params["feed"] {
+		for _, s := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || id <= 0 {
+				return q, fmt.Errorf("invalid feed id %q", s)
+			}
+			q.FeedIDs = append(q.FeedIDs, id)
+		}
+	}
+
+	var err error
+	if q.Since, err = parseTime(params.Get("since"), now); err != nil {
+		return q, fmt.Errorf("invalid since: %w", err)
+	}
+	if q.Until, err = parseTime(params.Get("until"), now); err != nil {
+		return q, fmt.Errorf("invalid until: %w", err)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return q, fmt.Errorf("since must be before until")
+	}
+
+	for _, keyword := range params["include"] {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			q.Include = append(q.Include, keyword)
+		}
+	}
+	for _, keyword := range params["exclude"] {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			q.Exclude = append(q.Exclude, keyword)
+		}
+	}
+	q.Author = strings.TrimSpace(params.Get("author"))
+
+	if len(q.FeedIDs) > maxFilterValues || len(q.Include) > maxFilterValues || len(q.Exclude) > maxFilterValues {
+		return q, fmt.Errorf("at most %d feeds or keywords per filter", maxFilterValues)
+	}
+	return q, nil
+}
+
+// parseTime понимает RFC 3339, дату 2006-01-02 (полночь UTC) и срок до
+// текущего момента: 36h, 7d. Пустая строка - нулевое время.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a RFC 3339 time, a date or a duration like 36h or 7d", s)
+}
+
+// pageSize читает параметр limit: от 1 до maxPageSize, по умолчанию defaultPageSize.
+func pageSize(w http.ResponseWriter, params url.Values) (int, bool) {
+	s := params.Get("limit")
+	if s == "" {
+		return defaultPageSize, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 || n > maxPageSize {
+		http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxPageSize), http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// cursorParam читает курсор из параметра name, nil если параметра нет.
+func cursorParam(w http.ResponseWriter, params url.Values, name string) (*db.Cursor, bool) {
+	s := params.Get(name)
+	if s == "" {
+		return nil, true
+	}
+	c, err := db.ParseCursor(s)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid %s cursor", name), http.StatusBadRequest)
+		return nil, false
+	}
+	return &c, true
+}
+
+// pageLink строит ссылку для заголовка Link (RFC 8288) на соседнюю
+// страницу, сохраняя остальные параметры запроса.
+func pageLink(r *http.Request, param, cursor, rel string) string {
+	params := r.URL.Query()
+	params.Del("after")
+	params.Del("before")
+	params.Set(param, cursor)
+	u := url.URL{Path: r.URL.Path, RawQuery: params.Encode()}
+	return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+}
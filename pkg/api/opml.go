@@ -0,0 +1,41 @@
+package api
+
+import (
+	"fmt"
+	"goNews/pkg/opml"
+	"net/http"
+	"time"
+)
+
+// maxOPMLSize ограничивает размер импортируемого списка подписок.
+const maxOPMLSize = 5 << 20
+
+// importOPMLHandler обслуживает POST /api/opml: тело запроса - документ
+// OPML, ленты из него добавляются, если их ещё нет.
+func (api *API) importOPMLHandler(w http.ResponseWriter, r *http.Request) {
+	subs, err := opml.Parse(http.MaxBytesReader(w, r.Body, maxOPMLSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := opml.Import(r.Context(), api.db, subs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to import feeds: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// exportOPMLHandler обслуживает GET /api/opml: все ленты с папками.
+func (api *API) exportOPMLHandler(w http.ResponseWriter, r *http.Request) {
+	subs, err := opml.Export(r.Context(), api.db)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to fetch feeds: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="gonews.opml"`)
+	if err := opml.New(syndicationTitle, subs, time.Now()).Write(w); err != nil {
+		fmt.Printf("failed to encode response: %v\n", err)
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// searchHandler обслуживает GET /api/search?q=...&limit=...: записи,
+// самые релевантные первыми, с фрагментами текста.
+func (api *API) searchHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "query parameter q is required", http.StatusBadRequest)
+		return
+	}
+	limit, ok := pageSize(w, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	found, err := api.db.Search(r.Context(), query, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to search news: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, found)
+}
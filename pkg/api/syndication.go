@@ -0,0 +1,343 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"goNews/pkg/db"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Заголовок и описание ленты, в которую собраны записи агрегатора.
+const (
+	syndicationTitle       = "goNews"
+	syndicationDescription = "Записи всех лент goNews"
+)
+
+// rssDocument - документ RSS 2.0. Автор записи передаётся в dc:creator,
+// потому что <author> по спецификации должен быть адресом почты.
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description,omitempty"`
+	Creator     string  `xml:"dc:creator,omitempty"`
+	Category    string  `xml:"category,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed - документ Atom (RFC 4287).
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Author    atomPerson  `xml:"author"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Links     []atomLink  `xml:"link"`
+	Published string      `xml:"published,omitempty"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author"`
+	Category  *atomTerm   `xml:"category"`
+	Summary   string      `xml:"summary,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+// jsonFeed - документ JSON Feed 1.1.
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// rssHandler обслуживает GET /feed.rss.
+func (api *API) rssHandler(w http.ResponseWriter, r *http.Request) {
+	news, ok := api.syndicatedNews(w, r)
+	if !ok {
+		return
+	}
+	self, home := feedURLs(r)
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         syndicationTitle,
+			Link:          home,
+			Description:   syndicationDescription,
+			Self:          atomLink{Href: self, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: lastUpdated(news).Format(time.RFC1123Z),
+			Generator:     syndicationTitle,
+			Items:         make([]rssItem, 0, len(news)),
+		},
+	}
+	for _, n := range news {
+		// description в RSS - HTML, а записи хранятся простым текстом
+		item := rssItem{
+			Title:       n.Name,
+			Link:        n.Link,
+			Description: html.EscapeString(n.Description),
+			Creator:     n.Author,
+			Category:    n.FeedTitle,
+			GUID:        rssGUID{Value: entryID(n)},
+		}
+		if !n.PublicationDate.IsZero() {
+			item.PubDate = n.PublicationDate.Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	writeXML(w, "application/rss+xml; charset=utf-8", doc)
+}
+
+// atomHandler обслуживает GET /feed.atom.
+func (api *API) atomHandler(w http.ResponseWriter, r *http.Request) {
+	news, ok := api.syndicatedNews(w, r)
+	if !ok {
+		return
+	}
+	self, home := feedURLs(r)
+	updated := lastUpdated(news)
+	feed := atomFeed{
+		ID:       self,
+		Title:    syndicationTitle,
+		Subtitle: syndicationDescription,
+		Updated:  updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: home, Rel: "alternate", Type: "text/html"},
+		},
+		Author:    atomPerson{Name: syndicationTitle},
+		Generator: syndicationTitle,
+		Entries:   make([]atomEntry, 0, len(news)),
+	}
+	for _, n := range news {
+		entry := atomEntry{
+			ID:      entryID(n),
+			Title:   n.Name,
+			Updated: updated.Format(time.RFC3339),
+			Summary: n.Description,
+		}
+		if n.Link != "" {
+			entry.Links = []atomLink{{Href: n.Link, Rel: "alternate"}}
+		}
+		if !n.PublicationDate.IsZero() {
+			entry.Published = n.PublicationDate.Format(time.RFC3339)
+			entry.Updated = entry.Published
+		}
+		if n.UpdatedAt != nil {
+			entry.Updated = n.UpdatedAt.Format(time.RFC3339)
+		}
+		if n.Author != "" {
+			entry.Author = &atomPerson{Name: n.Author}
+		}
+		if n.FeedTitle != "" {
+			entry.Category = &atomTerm{Term: n.FeedTitle}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	writeXML(w, "application/atom+xml; charset=utf-8", feed)
+}
+
+// jsonFeedHandler обслуживает GET /feed.json.
+func (api *API) jsonFeedHandler(w http.ResponseWriter, r *http.Request) {
+	news, ok := api.syndicatedNews(w, r)
+	if !ok {
+		return
+	}
+	self, home := feedURLs(r)
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       syndicationTitle,
+		Description: syndicationDescription,
+		HomePageURL: home,
+		FeedURL:     self,
+		Items:       make([]jsonFeedItem, 0, len(news)),
+	}
+	for _, n := range news {
+		item := jsonFeedItem{
+			ID:          entryID(n),
+			URL:         n.Link,
+			Title:       n.Name,
+			ContentText: n.Description,
+		}
+		if !n.PublicationDate.IsZero() {
+			item.DatePublished = n.PublicationDate.Format(time.RFC3339)
+		}
+		if n.UpdatedAt != nil {
+			item.DateModified = n.UpdatedAt.Format(time.RFC3339)
+		}
+		if n.Author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: n.Author}}
+		}
+		if n.FeedTitle != "" {
+			item.Tags = []string{n.FeedTitle}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(feed); err != nil {
+		fmt.Printf("failed to encode response: %v\n", err)
+	}
+}
+
+// syndicatedNews выбирает последние записи для ленты. Понимает limit и
+// фильтры /api/news, а q - слова, которые должны встретиться в записи.
+func (api *API) syndicatedNews(w http.ResponseWriter, r *http.Request) ([]db.News, bool) {
+	params := r.URL.Query()
+	limit, ok := pageSize(w, params)
+	if !ok {
+		return nil, false
+	}
+	q, err := parseFilters(params, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if query := strings.TrimSpace(params.Get("q")); query != "" {
+		q.Include = append(q.Include, query)
+	}
+	q.Limit = limit
+
+	page, err := api.db.News(r.Context(), q)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to fetch news: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return page.News, true
+}
+
+// feedURLs возвращает абсолютные адреса самой ленты, вместе с параметрами
+// запроса, и главной страницы. За обратным прокси схема берётся из
+// X-Forwarded-Proto.
+func feedURLs(r *http.Request) (self, home string) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	self = u.String()
+	u.Path, u.RawQuery = "/", ""
+	return self, u.String()
+}
+
+// lastUpdated - время последнего изменения ленты: самая поздняя публикация
+// или правка, для пустой ленты - текущее время.
+func lastUpdated(news []db.News) time.Time {
+	var last time.Time
+	for _, n := range news {
+		if n.PublicationDate.After(last) {
+			last = n.PublicationDate
+		}
+		if n.UpdatedAt != nil && n.UpdatedAt.After(last) {
+			last = *n.UpdatedAt
+		}
+	}
+	if last.IsZero() {
+		return time.Now().UTC().Truncate(time.Second)
+	}
+	return last
+}
+
+// idSchemes - схемы GUID источника, которые годятся в guid и Atom id как есть.
+var idSchemes = map[string]bool{"http": true, "https": true, "urn": true, "tag": true}
+
+// entryID - постоянный идентификатор записи для guid и Atom id. URI
+// известной схемы без пробелов из источника сохраняется как есть, иначе из
+// ленты и GUID выводится urn:uuid версии 5, чтобы одинаковые GUID разных
+// лент не совпадали. Проверки url.Parse мало: под неё попадает и заголовок
+// вида «Habr: новости недели», ставший GUID старых записей.
+func entryID(n db.News) string {
+	if u, err := url.Parse(n.GUID); err == nil && idSchemes[u.Scheme] &&
+		strings.IndexFunc(n.GUID, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) < 0 {
+		return n.GUID
+	}
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d\n%s", n.FeedID, n.GUID)))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// writeXML отправляет документ v с XML-декларацией.
+func writeXML(w http.ResponseWriter, contentType string, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Printf("failed to encode response: %v\n", err)
+	}
+}
@@ -0,0 +1,345 @@
+// Package config собирает настройки сервера из файла, переменных окружения
+// и флагов командной строки. Каждый следующий слой перекрывает предыдущий.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultPath - файл настроек, если он не указан явно.
+const DefaultPath = "./src/config.json"
+
+// Поддерживаемые хранилища.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Config - настройки сервера. Интервалы задаются в секундах.
+type Config struct {
+	// Listen - адрес HTTP-сервера.
+	Listen string `json:"listen" yaml:"listen"`
+	// WebappDir - каталог со статикой веб-интерфейса.
+	WebappDir string `json:"webapp_dir" yaml:"webapp_dir"`
+	// ShutdownTimeout - сколько ждать идущих загрузок и HTTP-запросов при остановке.
+	ShutdownTimeout int `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+
+	// Feeds - ленты, которые регистрируются при старте.
+	Feeds []string `json:"rss" yaml:"rss"`
+	// Period - интервал опроса лент без собственного расписания.
+	Period int `json:"request_period" yaml:"request_period"`
+	// Concurrency - число одновременных загрузок.
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+	// FetchTimeout - таймаут одной загрузки.
+	FetchTimeout int `json:"fetch_timeout" yaml:"fetch_timeout"`
+	// MaxFailures - после скольких ошибок подряд лента отключается,
+	// отрицательное значение - не отключать никогда.
+	MaxFailures int `json:"max_failures" yaml:"max_failures"`
+
+	Database Database `json:"database" yaml:"database"`
+
+	// Path - файл, из которого прочитаны настройки, пустой, если файла нет.
+	Path string `json:"-" yaml:"-"`
+	// Args - аргументы командной строки после флагов.
+	Args []string `json:"-" yaml:"-"`
+}
+
+// Database - где хранятся новости. Driver выбирает хранилище: postgres
+// или sqlite. Для Postgres URL, если задан, важнее отдельных полей;
+// для SQLite используется только Path.
+type Database struct {
+	Driver   string `json:"driver" yaml:"driver"`
+	Path     string `json:"path" yaml:"path"`
+	URL      string `json:"url" yaml:"url"`
+	Host     string `json:"host" yaml:"host"`
+	Port     int    `json:"port" yaml:"port"`
+	User     string `json:"user" yaml:"user"`
+	Password string `json:"password" yaml:"password"`
+	Name     string `json:"name" yaml:"name"`
+	SSLMode  string `json:"sslmode" yaml:"sslmode"`
+}
+
+// ConnString возвращает строку подключения к базе.
+func (d Database) ConnString() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, d.Password),
+		Host:   net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:   "/" + d.Name,
+	}
+	if d.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {d.SSLMode}}.Encode()
+	}
+	return u.String()
+}
+
+// Default возвращает настройки по умолчанию.
+func Default() *Config {
+	return &Config{
+		Listen:          ":8000",
+		WebappDir:       filepath.Join(".", "src", "webapp"),
+		ShutdownTimeout: 10,
+		Period:          60,
+		Concurrency:     4,
+		FetchTimeout:    30,
+		MaxFailures:     10,
+		Database: Database{
+			Driver: DriverPostgres,
+			Path:   filepath.Join(".", "data", "gonews.db"),
+			Host:   "localhost",
+			Port:   5432,
+			User:   "postgres",
+			Name:   "GoNews",
+		},
+	}
+}
+
+// Load собирает настройки: значения по умолчанию, файл (JSON или YAML по
+// расширению), переменные окружения и флаги из args. Файл по умолчанию
+// может отсутствовать, явно указанный - обязан существовать.
+func Load(args []string) (*Config, error) {
+	conf := Default()
+
+	fs := flag.NewFlagSet("goNews", flag.ContinueOnError)
+	flags := defineFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	conf.Args = fs.Args()
+
+	path, explicit := DefaultPath, false
+	if env := os.Getenv("GONEWS_CONFIG"); env != "" {
+		path, explicit = env, true
+	}
+	if *flags.config != "" {
+		path, explicit = *flags.config, true
+	}
+	if err := conf.loadFile(path); err == nil {
+		conf.Path = path
+	} else if explicit || !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err := conf.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := flags.apply(fs, conf); err != nil {
+		return nil, err
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(file, c)
+	default:
+		err = json.Unmarshal(file, c)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// setting связывает поле настроек с ключом файла, переменной окружения
+// и флагом. Заполнено ровно одно из str, num и list. restart отмечает
+// настройки, которые применяются только при запуске, secret - значения,
+// которые нельзя выводить в лог.
+type setting struct {
+	key     string
+	env     string
+	flag    string
+	usage   string
+	restart bool
+	secret  bool
+	str     func(c *Config) *string
+	num     func(c *Config) *int
+	list    func(c *Config) *[]string
+}
+
+var settings = []setting{
+	{key: "listen", env: "GONEWS_LISTEN", flag: "listen", restart: true, usage: "HTTP listen address",
+		str: func(c *Config) *string { return &c.Listen }},
+	{key: "webapp_dir", env: "GONEWS_WEBAPP_DIR", flag: "webapp-dir", restart: true, usage: "directory with static web files",
+		str: func(c *Config) *string { return &c.WebappDir }},
+	{key: "shutdown_timeout", env: "GONEWS_SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", restart: true, usage: "graceful shutdown timeout, seconds",
+		num: func(c *Config) *int { return &c.ShutdownTimeout }},
+	{key: "rss", env: "GONEWS_RSS", flag: "rss", usage: "comma-separated list of feed URLs",
+		list: func(c *Config) *[]string { return &c.Feeds }},
+	{key: "request_period", env: "GONEWS_REQUEST_PERIOD", flag: "request-period", usage: "default feed polling interval, seconds",
+		num: func(c *Config) *int { return &c.Period }},
+	{key: "concurrency", env: "GONEWS_CONCURRENCY", flag: "concurrency", usage: "number of concurrent feed fetches",
+		num: func(c *Config) *int { return &c.Concurrency }},
+	{key: "fetch_timeout", env: "GONEWS_FETCH_TIMEOUT", flag: "fetch-timeout", usage: "timeout of a single feed fetch, seconds",
+		num: func(c *Config) *int { return &c.FetchTimeout }},
+	{key: "max_failures", env: "GONEWS_MAX_FAILURES", flag: "max-failures", usage: "disable a feed after this many consecutive failures, negative to never",
+		num: func(c *Config) *int { return &c.MaxFailures }},
+	{key: "database.driver", env: "GONEWS_DB_DRIVER", flag: "db-driver", restart: true, usage: "storage backend: postgres or sqlite",
+		str: func(c *Config) *string { return &c.Database.Driver }},
+	{key: "database.path", env: "GONEWS_DB_PATH", flag: "db-path", restart: true, usage: "SQLite database file",
+		str: func(c *Config) *string { return &c.Database.Path }},
+	{key: "database.url", env: "DATABASE_URL", flag: "database-url", restart: true, secret: true, usage: "full Postgres connection URL",
+		str: func(c *Config) *string { return &c.Database.URL }},
+	{key: "database.host", env: "GONEWS_DB_HOST", flag: "db-host", restart: true, usage: "Postgres host",
+		str: func(c *Config) *string { return &c.Database.Host }},
+	{key: "database.port", env: "GONEWS_DB_PORT", flag: "db-port", restart: true, usage: "Postgres port",
+		num: func(c *Config) *int { return &c.Database.Port }},
+	{key: "database.user", env: "GONEWS_DB_USER", flag: "db-user", restart: true, usage: "Postgres user",
+		str: func(c *Config) *string { return &c.Database.User }},
+	// dbpass оставлена для совместимости со старым запуском
+	{env: "dbpass",
+		str: func(c *Config) *string { return &c.Database.Password }},
+	{key: "database.password", env: "GONEWS_DB_PASSWORD", flag: "db-password", restart: true, secret: true, usage: "Postgres password",
+		str: func(c *Config) *string { return &c.Database.Password }},
+	{key: "database.name", env: "GONEWS_DB_NAME", flag: "db-name", restart: true, usage: "Postgres database name",
+		str: func(c *Config) *string { return &c.Database.Name }},
+	{key: "database.sslmode", env: "GONEWS_DB_SSLMODE", flag: "db-sslmode", restart: true, usage: "Postgres sslmode",
+		str: func(c *Config) *string { return &c.Database.SSLMode }},
+}
+
+// set разбирает значение из окружения или флага и записывает его в поле.
+func (s setting) set(c *Config, value string) error {
+	switch {
+	case s.str != nil:
+		*s.str(c) = value
+	case s.num != nil:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*s.num(c) = n
+	case s.list != nil:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*s.list(c) = list
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(c, value); err != nil {
+				return fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	return nil
+}
+
+// flagValues хранит флаги в виде строк: применяются только явно заданные,
+// поэтому флаги не перекрывают файл и окружение своими значениями по умолчанию.
+type flagValues struct {
+	config *string
+	values map[string]*string
+}
+
+func defineFlags(fs *flag.FlagSet) *flagValues {
+	f := &flagValues{
+		config: fs.String("config", "", "path to a JSON or YAML config file (default "+DefaultPath+")"),
+		values: make(map[string]*string),
+	}
+	for _, s := range settings {
+		if s.flag != "" {
+			f.values[s.flag] = fs.String(s.flag, "", s.usage)
+		}
+	}
+	return f
+}
+
+func (f *flagValues) apply(fs *flag.FlagSet, c *Config) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == fl.Name && err == nil {
+				if setErr := s.set(c, *f.values[fl.Name]); setErr != nil {
+					err = fmt.Errorf("-%s: %w", fl.Name, setErr)
+				}
+			}
+		}
+	})
+	return err
+}
+
+// Validate проверяет настройки целиком и возвращает все найденные ошибки сразу.
+func (c *Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("listen: invalid address %q", c.Listen))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout must be positive"))
+	}
+	if c.Period <= 0 {
+		errs = append(errs, errors.New("request_period must be positive"))
+	}
+	if c.Concurrency <= 0 {
+		errs = append(errs, errors.New("concurrency must be positive"))
+	}
+	if c.FetchTimeout <= 0 {
+		errs = append(errs, errors.New("fetch_timeout must be positive"))
+	}
+	if c.MaxFailures == 0 {
+		errs = append(errs, errors.New("max_failures must not be zero"))
+	}
+	for _, link := range c.Feeds {
+		if u, err := url.Parse(link); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("rss: %q is not an absolute http(s) url", link))
+		}
+	}
+
+	switch {
+	case c.Database.Driver == DriverSQLite:
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path must not be empty for sqlite"))
+		}
+	case c.Database.Driver != DriverPostgres:
+		errs = append(errs, fmt.Errorf("database.driver %q is not one of postgres, sqlite", c.Database.Driver))
+	case c.Database.URL != "":
+		u, err := url.Parse(c.Database.URL)
+		if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			errs = append(errs, errors.New("database.url must be a postgres:// url"))
+		}
+	default:
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host must not be empty"))
+		}
+		if c.Database.Port <= 0 || c.Database.Port > 65535 {
+			errs = append(errs, fmt.Errorf("database.port %d is out of range", c.Database.Port))
+		}
+		if c.Database.User == "" || c.Database.Name == "" {
+			errs = append(errs, errors.New("database.user and database.name must not be empty"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoad проверяет наложение файла, окружения и флагов
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "config.json")
+	yamlPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(jsonPath, []byte(`{
+		"rss": ["https://example.com/rss"],
+		"request_period": 5,
+		"database": {"host": "db", "name": "news"}
+	}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(yamlPath, []byte("listen: \":9000\"\nrss:\n  - https://example.com/a\n  - https://example.com/b\nconcurrency: 8\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantErr bool
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name:    "explicit missing file",
+			args:    []string{"-config", filepath.Join(dir, "missing.json")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid env number",
+			args:    []string{"-config", jsonPath},
+			env:     map[string]string{"GONEWS_CONCURRENCY": "many"},
+			wantErr: true,
+		},
+		{
+			name: "json file keeps other defaults",
+			args: []string{"-config", jsonPath},
+			check: func(t *testing.T, c *Config) {
+				if c.Period != 5 || len(c.Feeds) != 1 || c.Listen != ":8000" || c.Concurrency != 4 || c.Path != jsonPath {
+					t.Errorf("Unexpected config: %+v", c)
+				}
+				if got := c.Database.ConnString(); got != "postgres://postgres:@db:5432/news" {
+					t.Errorf("Unexpected connection string %q", got)
+				}
+			},
+		},
+		{
+			name: "yaml file",
+			args: []string{"-config", yamlPath},
+			check: func(t *testing.T, c *Config) {
+				if c.Listen != ":9000" || c.Concurrency != 8 || len(c.Feeds) != 2 {
+					t.Errorf("Unexpected config: %+v", c)
+				}
+			},
+		},
+		{
+			name: "env overrides file",
+			args: []string{"-config", jsonPath},
+			env:  map[string]string{"GONEWS_REQUEST_PERIOD": "30", "dbpass": "secret", "GONEWS_DB_PORT": "6432"},
+			check: func(t *testing.T, c *Config) {
+				if c.Period != 30 || c.Database.Password != "secret" || c.Database.Port != 6432 {
+					t.Errorf("Unexpected config: %+v", c)
+				}
+			},
+		},
+		{
+			name: "flags override env",
+			args: []string{"-config", jsonPath, "-request-period", "15", "-rss", "https://a.example/rss, https://b.example/rss"},
+			env:  map[string]string{"GONEWS_REQUEST_PERIOD": "30"},
+			check: func(t *testing.T, c *Config) {
+				if c.Period != 15 || len(c.Feeds) != 2 || c.Feeds[1] != "https://b.example/rss" {
+					t.Errorf("Unexpected config: %+v", c)
+				}
+			},
+		},
+		{
+			name: "positional args after flags",
+			args: []string{"-config", jsonPath, "down", "2"},
+			check: func(t *testing.T, c *Config) {
+				if len(c.Args) != 2 || c.Args[0] != "down" || c.Args[1] != "2" {
+					t.Errorf("Unexpected args: %v", c.Args)
+				}
+			},
+		},
+		{
+			name: "database url wins over fields",
+			args: []string{"-config", jsonPath},
+			env:  map[string]string{"DATABASE_URL": "postgres://u:p@pg:5433/prod?sslmode=require"},
+			check: func(t *testing.T, c *Config) {
+				if got := c.Database.ConnString(); got != "postgres://u:p@pg:5433/prod?sslmode=require" {
+					t.Errorf("Unexpected connection string %q", got)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Окружение машины не должно влиять на тест
+			for _, s := range settings {
+				t.Setenv(s.env, "")
+				os.Unsetenv(s.env)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			c, err := Load(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got config %+v", c)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+// TestValidate проверяет, что ошибки настроек собираются вместе
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		errors []string
+	}{
+		{name: "defaults are valid", modify: func(c *Config) {}},
+		{
+			name: "several errors at once",
+			modify: func(c *Config) {
+				c.Listen = "8000"
+				c.Concurrency = 0
+				c.Feeds = []string{"ftp://example.com/rss"}
+			},
+			errors: []string{"listen", "concurrency", "ftp://example.com/rss"},
+		},
+		{
+			name:   "bad database url",
+			modify: func(c *Config) { c.Database.URL = "mysql://localhost/db" },
+			errors: []string{"database.url"},
+		},
+		{
+			name: "sqlite ignores postgres fields",
+			modify: func(c *Config) {
+				c.Database.Driver = DriverSQLite
+				c.Database.Host = ""
+			},
+		},
+		{
+			name: "sqlite without path",
+			modify: func(c *Config) {
+				c.Database.Driver = DriverSQLite
+				c.Database.Path = ""
+			},
+			errors: []string{"database.path"},
+		},
+		{
+			name:   "unknown driver",
+			modify: func(c *Config) { c.Database.Driver = "mysql" },
+			errors: []string{"database.driver"},
+		},
+		{
+			name:   "bad database port",
+			modify: func(c *Config) { c.Database.Port = 70000 },
+			errors: []string{"database.port"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.modify(c)
+			err := c.Validate()
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected validation error")
+			}
+			for _, part := range tt.errors {
+				if !strings.Contains(err.Error(), part) {
+					t.Errorf("Expected %q in error %v", part, err)
+				}
+			}
+		})
+	}
+}
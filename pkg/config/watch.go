@@ -0,0 +1,119 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Diff описывает отличия next от c построчно, в терминах ключей файла
+// настроек. Секретные значения не выводятся, а настройки, которые
+// применяются только при запуске, помечаются.
+func (c *Config) Diff(next *Config) []string {
+	var lines []string
+	for _, s := range settings {
+		if s.key == "" {
+			continue
+		}
+		var changes []string
+		switch {
+		case s.str != nil:
+			if old, cur := *s.str(c), *s.str(next); old != cur {
+				changes = append(changes, fmt.Sprintf("%q -> %q", old, cur))
+			}
+		case s.num != nil:
+			if old, cur := *s.num(c), *s.num(next); old != cur {
+				changes = append(changes, fmt.Sprintf("%d -> %d", old, cur))
+			}
+		case s.list != nil:
+			changes = listDiff(*s.list(c), *s.list(next))
+		}
+
+		for _, change := range changes {
+			if s.secret {
+				change = "changed"
+			}
+			line := s.key + ": " + change
+			if s.restart {
+				line += " (requires restart)"
+			}
+			lines = append(lines, line)
+			if s.secret {
+				break
+			}
+		}
+	}
+	return lines
+}
+
+// listDiff возвращает удалённые (-) и добавленные (+) элементы списка.
+func listDiff(old, cur []string) []string {
+	inOld := make(map[string]bool, len(old))
+	for _, item := range old {
+		inOld[item] = true
+	}
+	inCur := make(map[string]bool, len(cur))
+	for _, item := range cur {
+		inCur[item] = true
+	}
+
+	var changes []string
+	for _, item := range old {
+		if !inCur[item] {
+			changes = append(changes, "- "+item)
+		}
+	}
+	for _, item := range cur {
+		if !inOld[item] {
+			changes = append(changes, "+ "+item)
+		}
+	}
+	return changes
+}
+
+// Watch раз в every проверяет время изменения и размер файла path и
+// сообщает в канал, когда они меняются. Несколько изменений между чтениями
+// канала сливаются в одно. Канал закрывается при отмене контекста.
+func Watch(ctx context.Context, path string, every time.Duration) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer close(changed)
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		last := stat(path)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			cur := stat(path)
+			if cur.modTime.Equal(last.modTime) && cur.size == last.size {
+				continue
+			}
+			last = cur
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changed
+}
+
+// fileState - то, по чему Watch замечает изменение файла. Отсутствующий
+// файл даёт нулевое значение, поэтому появление и удаление тоже заметны.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func stat(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}
+}
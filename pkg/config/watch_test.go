@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestDiff проверяет описание изменений при перечитывании настроек
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{name: "no changes", modify: func(c *Config) {}},
+		{
+			name: "feeds and period",
+			modify: func(c *Config) {
+				c.Feeds = []string{"https://b.example/rss", "https://c.example/rss"}
+				c.Period = 30
+			},
+			want: []string{
+				"rss: - https://a.example/rss",
+				"rss: + https://c.example/rss",
+				"request_period: 60 -> 30",
+			},
+		},
+		{
+			name: "restart settings and secrets",
+			modify: func(c *Config) {
+				c.Listen = ":9000"
+				c.Database.Password = "secret"
+			},
+			want: []string{
+				`listen: ":8000" -> ":9000" (requires restart)`,
+				"database.password: changed (requires restart)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := Default()
+			old.Feeds = []string{"https://a.example/rss", "https://b.example/rss"}
+			next := Default()
+			next.Feeds = append([]string(nil), old.Feeds...)
+			tt.modify(next)
+
+			if got := old.Diff(next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWatch проверяет, что изменение файла замечается
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"request_period": 60}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := Watch(ctx, path, 10*time.Millisecond)
+
+	select {
+	case <-changed:
+		t.Fatal("Unexpected change notification")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte(`{"request_period": 30}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("Change was not noticed")
+	}
+
+	cancel()
+	for range changed {
+	}
+}
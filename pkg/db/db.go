@@ -2,10 +2,15 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4/pgxpool"
-	"os"
-	"strconv"
+	"goNews/pkg/config"
+	"goNews/pkg/supervisor"
+	"strings"
 	"time"
 )
 
@@ -13,24 +18,48 @@ type DB struct {
 	Pool *pgxpool.Pool
 }
 
-const (
-	host   = "localhost"
-	port   = 5432
-	user   = "postgres"
-	dbname = "GoNews"
-)
-
 type News struct {
-	Name            string `json:"name"`
-	Description     string `json:"description"`
-	PublicationDate string `json:"publication_date"`
-	Link            string `json:"link"`
+	ID              int       `json:"id"`
+	FeedID          int       `json:"feed_id"`
+	FeedTitle       string    `json:"feed_title"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	PublicationDate time.Time `json:"publication_date"`
+	// Link - ссылка на саму статью.
+	Link string `json:"link"`
+	// Author - автор записи, если лента его указывает.
+	Author string `json:"author"`
+	// GUID - идентификатор записи в пределах источника: <guid>, Atom <id>
+	// или канонизированная ссылка на статью.
+	GUID string `json:"guid"`
+	// ContentHash - хеш заголовка и содержимого, по которому замечаются правки.
+	ContentHash string `json:"-"`
+	// UpdatedAt - время последней правки, nil если запись не менялась.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// Revisions - число сохранённых предыдущих версий.
+	Revisions int `json:"revisions"`
 }
 
-func New(ctx context.Context, errCn chan<- error) *DB {
-	db := &DB{}
-	pwd := os.Getenv("dbpass")
-	connStr := "postgres://" + user + ":" + pwd + "@" + host + ":" + strconv.Itoa(port) + "/" + dbname
+// New подключается к базе и применяет недостающие миграции.
+func New(ctx context.Context, conf config.Database, errCn chan<- error) *DB {
+	db, err := Connect(ctx, conf)
+	if err != nil {
+		errCn <- err
+		return nil
+	}
+
+	if err := db.Migrate(ctx); err != nil {
+		errCn <- err
+		db.Pool.Close()
+		return nil
+	}
+
+	return db
+}
+
+// Connect подключается к базе, не трогая схему.
+func Connect(ctx context.Context, conf config.Database) (*DB, error) {
+	connStr := conf.ConnString()
 
 	// Подключение с повторными попытками
 	maxRetries := 10
@@ -46,63 +75,213 @@ func New(ctx context.Context, errCn chan<- error) *DB {
 			}
 			pool.Close()
 		}
+		if err = unrecoverable(err); supervisor.IsFatal(err) {
+			return nil, err
+		}
 		fmt.Printf("Попытка %d: не удалось подключиться к базе данных: %v, ждём %v\n", i+1, err, retryDelay)
 		time.Sleep(retryDelay)
 	}
 	if err != nil {
-		errCn <- fmt.Errorf("failed to connect to database after %d retries: %w", maxRetries, err)
-		return nil
+		return nil, fmt.Errorf("failed to connect to database after %d retries: %w", maxRetries, err)
 	}
 
-	db.Pool = pool
+	return &DB{Pool: pool}, nil
+}
 
-	// Создание таблицы news, если она не существует
-	_, err = db.Pool.Exec(ctx, `
-		CREATE TABLE IF NOT EXISTS news (
-			id SERIAL PRIMARY KEY,
-			name TEXT UNIQUE,
-			description TEXT,
-			publication_date TEXT,
-			link TEXT
-		);
-	`)
-	if err != nil {
-		errCn <- fmt.Errorf("failed to create table news: %w", err)
-		db.Pool.Close()
-		return nil
+// unrecoverable помечает неустранимыми ошибки авторизации (класс SQLSTATE 28):
+// неверный пароль или нет прав на базу не исправятся ни повтором, ни
+// перезапуском подсистемы.
+func unrecoverable(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "28") {
+		return supervisor.Fatal(fmt.Errorf("database authentication failed: %w", err))
 	}
-
-	return db
+	return err
 }
 
-func (db *DB) News(ctx context.Context, col int) ([]News, error) {
+// News возвращает страницу записей по q, новые первыми. Страницы
+// отсчитываются от курсоров по индексу (publication_date, id), а не смещением,
+// поэтому глубокие страницы стоят столько же, сколько первая.
+func (db *DB) News(ctx context.Context, q NewsQuery) (NewsPage, error) {
 	if db.Pool == nil {
-		return nil, fmt.Errorf("database pool is not initialized")
+		return NewsPage{}, fmt.Errorf("database pool is not initialized")
+	}
+
+	// Значения фильтров передаются только параметрами
+	args := []interface{}{q.Limit + 1}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := newsFilter(q, arg)
+	order := "DESC"
+	switch {
+	case q.After != nil:
+		conds = append(conds, fmt.Sprintf("(n.publication_date, n.id) < (%s, %s)", arg(q.After.PublishedAt), arg(q.After.ID)))
+	case q.Before != nil:
+		conds = append(conds, fmt.Sprintf("(n.publication_date, n.id) > (%s, %s)", arg(q.Before.PublishedAt), arg(q.Before.ID)))
+		order = "ASC"
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
 	}
 
 	result := make([]News, 0)
-	rows, err := db.Pool.Query(ctx,
-		"SELECT name, description, publication_date, link FROM news ORDER BY id DESC LIMIT $1;",
-		col)
+	rows, err := db.Pool.Query(ctx, `
+		SELECT n.id, COALESCE(n.feed_id, 0), COALESCE(NULLIF(f.custom_title, ''), f.title, ''), n.name, n.description, n.publication_date,
+			n.link, n.author, n.guid, n.updated_at,
+			(SELECT count(*) FROM news_revisions r WHERE r.news_id = n.id)
+		FROM news n
+		LEFT JOIN feeds f ON f.id = n.feed_id
+		`+where+`
+		ORDER BY n.publication_date `+order+`, n.id `+order+` LIMIT $1;`,
+		args...)
 	if err != nil {
-		return nil, fmt.Errorf("query error: %w", err)
+		return NewsPage{}, fmt.Errorf("query error: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var news News
-		if err := rows.Scan(&news.Name, &news.Description, &news.PublicationDate, &news.Link); err != nil {
+		if err := rows.Scan(&news.ID, &news.FeedID, &news.FeedTitle, &news.Name, &news.Description, &news.PublicationDate,
+			&news.Link, &news.Author, &news.GUID, &news.UpdatedAt, &news.Revisions); err != nil {
 			fmt.Printf("scan error: %v\n", err)
 			continue
 		}
+		// Отдаём даты в UTC, чтобы JSON не зависел от часового пояса сервера
+		news.PublicationDate = news.PublicationDate.UTC()
+		if news.UpdatedAt != nil {
+			updated := news.UpdatedAt.UTC()
+			news.UpdatedAt = &updated
+		}
 		result = append(result, news)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows error: %w", err)
+		return NewsPage{}, fmt.Errorf("rows error: %w", err)
+	}
+
+	return PageOf(q, result), nil
+}
+
+// newsFilter переводит фильтры q в условия WHERE; arg добавляет значение
+// в параметры запроса и возвращает его плейсхолдер. Слова ищутся по
+// полнотекстовому индексу с теми же конфигурациями, что и в Search.
+func newsFilter(q NewsQuery, arg func(v interface{}) string) []string {
+	var conds []string
+	if len(q.FeedIDs) > 0 {
+		conds = append(conds, "n.feed_id = ANY("+arg(q.FeedIDs)+"::integer[])")
 	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "n.publication_date >= "+arg(q.Since))
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "n.publication_date < "+arg(q.Until))
+	}
+	match := "n.search @@ (plainto_tsquery('russian', %[1]s::text) || plainto_tsquery('english', %[1]s::text))"
+	for _, keyword := range q.Include {
+		conds = append(conds, fmt.Sprintf(match, arg(keyword)))
+	}
+	for _, keyword := range q.Exclude {
+		conds = append(conds, "NOT "+fmt.Sprintf(match, arg(keyword)))
+	}
+	if q.Author != "" {
+		conds = append(conds, "lower(n.author) = lower("+arg(q.Author)+")")
+	}
+	return conds
+}
+
+// StoreNews сохраняет пачку записей. Новые записи добавляются, у известных
+// по паре (лента, guid) при смене хеша содержимого обновляются заголовок
+// и описание, а прежняя версия уходит в news_revisions.
+func (db *DB) StoreNews(ctx context.Context, news []News) error {
+	news = uniqueNews(news)
+	if len(news) == 0 {
+		return nil
+	}
+
+	keys := make([]interface{}, 0, len(news)*3)
+	keyPlaceholders := make([]string, 0, len(news))
+	values := make([]interface{}, 0, len(news)*8)
+	placeholders := make([]string, 0, len(news))
+	for i, n := range news {
+		hash := n.ContentHash
+		if hash == "" {
+			hash = ContentHash(n.Name, n.Description)
+		}
+		keys = append(keys, n.FeedID, n.GUID, hash)
+		keyPlaceholders = append(keyPlaceholders, fmt.Sprintf("($%d::integer, $%d::text, $%d::text)", i*3+1, i*3+2, i*3+3))
+		values = append(values, n.FeedID, n.Name, n.Description, n.PublicationDate, n.Link, n.Author, n.GUID, hash)
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			i*8+1, i*8+2, i*8+3, i*8+4, i*8+5, i*8+6, i*8+7, i*8+8))
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-	return result, nil
+	_, err = tx.Exec(ctx, `
+		INSERT INTO news_revisions (news_id, name, description, content_hash)
+		SELECT n.id, n.name, n.description, n.content_hash
+		FROM news n
+		JOIN (VALUES `+strings.Join(keyPlaceholders, ",")+`) AS v (feed_id, guid, content_hash)
+			ON n.feed_id = v.feed_id AND n.guid = v.guid
+		WHERE n.content_hash IS NOT NULL AND n.content_hash <> v.content_hash;`,
+		keys...)
+	if err != nil {
+		return fmt.Errorf("failed to save revisions: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO news (feed_id, name, description, publication_date, link, author, guid, content_hash)
+		VALUES `+strings.Join(placeholders, ",")+`
+		ON CONFLICT (feed_id, guid) DO UPDATE SET
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			link = EXCLUDED.link,
+			author = EXCLUDED.author,
+			content_hash = EXCLUDED.content_hash,
+			updated_at = CASE WHEN news.content_hash IS NULL THEN news.updated_at ELSE now() END
+		WHERE news.content_hash IS DISTINCT FROM EXCLUDED.content_hash;`,
+		values...)
+	if err != nil {
+		return fmt.Errorf("batch insert error: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// uniqueNews оставляет первую запись для каждой пары (лента, guid):
+// ON CONFLICT DO UPDATE не может затронуть одну строку дважды за запрос.
+func uniqueNews(news []News) []News {
+	type key struct {
+		feedID int
+		guid   string
+	}
+	seen := make(map[key]bool, len(news))
+	result := make([]News, 0, len(news))
+	for _, n := range news {
+		k := key{n.FeedID, n.GUID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		result = append(result, n)
+	}
+	return result
+}
+
+// ContentHash считает хеш содержимого записи для обнаружения правок.
+func ContentHash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 func (db *DB) Close() {
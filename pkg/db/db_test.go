@@ -4,27 +4,40 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
-	"github.com/jackc/pgx/v4/pgxpool"
+	"goNews/pkg/config"
 )
 
+// testDB - параметры тестовой базы: значения по умолчанию,
+// переопределённые переменными окружения (dbpass, DATABASE_URL).
+var testDB config.Database
+
 // TestMain подготавливает тестовую базу данных
 func TestMain(m *testing.M) {
 	ctx := context.Background()
-	pwd := os.Getenv("dbpass")
-	connStr := "postgres://postgres:" + pwd + "@localhost:5432/GoNews?sslmode=disable"
-
-	// Подключаемся к базе данных
-	pool, err := pgxpool.Connect(ctx, connStr)
+	conf, err := config.Load(nil)
 	if err != nil {
-		fmt.Printf("Failed to connect to database: %v\n", err)
+		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
 	}
+	testDB = conf.Database
+
+	// Подключаемся к базе данных и применяем миграции
+	errChan := make(chan error, 1)
+	dbInstance := New(ctx, testDB, errChan)
+	if dbInstance == nil {
+		fmt.Printf("Failed to initialize database: %v\n", <-errChan)
+		os.Exit(1)
+	}
+	pool := dbInstance.Pool
 	defer pool.Close()
 
-	// Очищаем таблицу news перед тестами
-	_, err = pool.Exec(ctx, "TRUNCATE TABLE news RESTART IDENTITY;")
+	// Очищаем таблицы перед тестами
+	_, err = pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;")
 	if err != nil {
 		fmt.Printf("Failed to truncate news table: %v\n", err)
 		os.Exit(1)
@@ -33,8 +46,8 @@ func TestMain(m *testing.M) {
 	// Запускаем тесты
 	code := m.Run()
 
-	// Очищаем таблицу после тестов
-	_, err = pool.Exec(ctx, "TRUNCATE TABLE news RESTART IDENTITY;")
+	// Очищаем таблицы после тестов
+	_, err = pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;")
 	if err != nil {
 		fmt.Printf("Failed to clean up news table: %v\n", err)
 	}
@@ -67,7 +80,7 @@ func TestNew(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setup()
-			dbInstance := New(ctx, errChan)
+			dbInstance := New(ctx, testDB, errChan)
 			if tt.expected {
 				if dbInstance == nil {
 					select {
@@ -102,7 +115,7 @@ func TestNew(t *testing.T) {
 func TestNews(t *testing.T) {
 	ctx := context.Background()
 	errChan := make(chan error, 1)
-	dbInstance := New(ctx, errChan)
+	dbInstance := New(ctx, testDB, errChan)
 	if dbInstance == nil {
 		t.Fatalf("Failed to initialize database: %v", <-errChan)
 	}
@@ -110,12 +123,12 @@ func TestNews(t *testing.T) {
 
 	// Вставляем тестовые данные
 	_, err := dbInstance.Pool.Exec(ctx, `
-		INSERT INTO news (name, description, publication_date, link)
+		INSERT INTO news (name, description, publication_date, link, guid)
 		VALUES 
-			('Test News 1', 'Description 1', '2023-01-01', 'http://example.com/1'),
-			('Test News 2', 'Description 2', '2023-01-02', 'http://example.com/2'),
-			('Test News 3', 'Description 3', '2023-01-03', 'http://example.com/3')
-		ON CONFLICT (name) DO NOTHING;
+			('Test News 1', 'Description 1', '2023-01-01', 'http://example.com/1', 'guid-1'),
+			('Test News 2', 'Description 2', '2023-01-02', 'http://example.com/2', 'guid-2'),
+			('Test News 3', 'Description 3', '2023-01-03', 'http://example.com/3', 'guid-3')
+		ON CONFLICT (feed_id, guid) DO NOTHING;
 	`)
 	if err != nil {
 		t.Fatalf("Failed to insert test data: %v", err)
@@ -149,7 +162,8 @@ func TestNews(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			news, err := dbInstance.News(ctx, tt.col)
+			page, err := dbInstance.News(ctx, NewsQuery{Limit: tt.col})
+			news := page.News
 			if tt.expectError {
 				if err == nil {
 					t.Errorf("Expected error, got nil")
@@ -163,11 +177,11 @@ func TestNews(t *testing.T) {
 				t.Errorf("Expected %d news items, got %d", tt.expectedLength, len(news))
 			}
 
-			// Проверяем порядок (DESC по id)
+			// Проверяем порядок (DESC по дате публикации)
 			if len(news) > 1 {
 				for i := 1; i < len(news); i++ {
-					if news[i].PublicationDate > news[i-1].PublicationDate {
-						t.Errorf("News items not sorted in descending order by id: %v", news)
+					if news[i].PublicationDate.After(news[i-1].PublicationDate) {
+						t.Errorf("News items not sorted in descending order by publication date: %v", news)
 					}
 				}
 			}
@@ -179,7 +193,7 @@ func TestNews(t *testing.T) {
 func TestClose(t *testing.T) {
 	ctx := context.Background()
 	errChan := make(chan error, 1)
-	dbInstance := New(ctx, errChan)
+	dbInstance := New(ctx, testDB, errChan)
 	if dbInstance == nil {
 		t.Fatalf("Failed to initialize database: %v", <-errChan)
 	}
@@ -196,3 +210,350 @@ func TestClose(t *testing.T) {
 	// Повторный вызов Close не должен паниковать
 	dbInstance.Close()
 }
+
+// TestNewsOrder проверяет, что порядок определяется датой публикации, а не порядком вставки
+func TestNewsOrder(t *testing.T) {
+	ctx := context.Background()
+	errChan := make(chan error, 1)
+	dbInstance := New(ctx, testDB, errChan)
+	if dbInstance == nil {
+		t.Fatalf("Failed to initialize database: %v", <-errChan)
+	}
+	defer dbInstance.Close()
+
+	if _, err := dbInstance.Pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;"); err != nil {
+		t.Fatalf("Failed to truncate news table: %v", err)
+	}
+	_, err := dbInstance.Pool.Exec(ctx, `
+		INSERT INTO news (name, description, publication_date, link, guid)
+		VALUES
+			('Middle', 'Description', '2023-01-02T12:00:00+03:00', 'http://example.com/1', 'middle'),
+			('Newest', 'Description', '2023-01-03T00:00:00Z', 'http://example.com/2', 'newest'),
+			('Oldest', 'Description', '2023-01-01T00:00:00Z', 'http://example.com/3', 'oldest');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	page, err := dbInstance.News(ctx, NewsQuery{Limit: 3})
+	news := page.News
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"Newest", "Middle", "Oldest"}
+	if len(news) != len(expected) {
+		t.Fatalf("Expected %d news items, got %d", len(expected), len(news))
+	}
+	for i, name := range expected {
+		if news[i].Name != name {
+			t.Errorf("Position %d: expected %q, got %q", i, name, news[i].Name)
+		}
+	}
+	if !news[1].PublicationDate.Equal(time.Date(2023, 1, 2, 9, 0, 0, 0, time.UTC)) || news[1].PublicationDate.Location() != time.UTC {
+		t.Errorf("Expected publication date in UTC, got %v", news[1].PublicationDate)
+	}
+}
+
+// TestStoreNews проверяет дедупликацию записей по guid в пределах источника
+func TestStoreNews(t *testing.T) {
+	ctx := context.Background()
+	errChan := make(chan error, 1)
+	dbInstance := New(ctx, testDB, errChan)
+	if dbInstance == nil {
+		t.Fatalf("Failed to initialize database: %v", <-errChan)
+	}
+	defer dbInstance.Close()
+
+	if _, err := dbInstance.Pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;"); err != nil {
+		t.Fatalf("Failed to truncate news table: %v", err)
+	}
+
+	feedA, err := dbInstance.AddFeed(ctx, "http://feed-a.com/rss")
+	if err != nil {
+		t.Fatalf("Failed to add feed: %v", err)
+	}
+	feedB, err := dbInstance.AddFeed(ctx, "http://feed-b.com/rss")
+	if err != nil {
+		t.Fatalf("Failed to add feed: %v", err)
+	}
+
+	published := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	batches := [][]News{
+		{
+			{FeedID: feedA.ID, Name: "Same headline", Description: "First", PublicationDate: published, GUID: "a-1"},
+			{FeedID: feedA.ID, Name: "Same headline", Description: "Second", PublicationDate: published, GUID: "a-2"},
+			{FeedID: feedB.ID, Name: "Same headline", Description: "Other feed", PublicationDate: published, GUID: "a-1"},
+		},
+		{
+			{FeedID: feedA.ID, Name: "Edited headline", Description: "First", PublicationDate: published, GUID: "a-1"},
+		},
+	}
+	for _, batch := range batches {
+		if err := dbInstance.StoreNews(ctx, batch); err != nil {
+			t.Fatalf("Failed to store news: %v", err)
+		}
+	}
+
+	var count int
+	if err := dbInstance.Pool.QueryRow(ctx, "SELECT count(*) FROM news;").Scan(&count); err != nil {
+		t.Fatalf("Failed to count news: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 distinct news items, got %d", count)
+	}
+}
+
+// TestStoreNewsRevisions проверяет обновление изменённых записей и сохранение прежних версий
+func TestStoreNewsRevisions(t *testing.T) {
+	ctx := context.Background()
+	errChan := make(chan error, 1)
+	dbInstance := New(ctx, testDB, errChan)
+	if dbInstance == nil {
+		t.Fatalf("Failed to initialize database: %v", <-errChan)
+	}
+	defer dbInstance.Close()
+
+	if _, err := dbInstance.Pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;"); err != nil {
+		t.Fatalf("Failed to truncate news table: %v", err)
+	}
+
+	feed, err := dbInstance.AddFeed(ctx, "http://feed.com/rss")
+	if err != nil {
+		t.Fatalf("Failed to add feed: %v", err)
+	}
+
+	item := News{FeedID: feed.ID, Name: "Typo in titel", Description: "Body", PublicationDate: time.Now(),
+		Link: "http://feed.com/1", GUID: "1"}
+	steps := []struct {
+		name      string
+		title     string
+		revisions int
+		edited    bool
+	}{
+		{name: "First insert", title: "Typo in titel", revisions: 0, edited: false},
+		{name: "Same content again", title: "Typo in titel", revisions: 0, edited: false},
+		{name: "Corrected title", title: "Typo in title", revisions: 1, edited: true},
+		{name: "Corrected twice", title: "No typo in title", revisions: 2, edited: true},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			item.Name = step.title
+			if err := dbInstance.StoreNews(ctx, []News{item}); err != nil {
+				t.Fatalf("Failed to store news: %v", err)
+			}
+
+			page, err := dbInstance.News(ctx, NewsQuery{Limit: 10})
+			news := page.News
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(news) != 1 {
+				t.Fatalf("Expected 1 news item, got %d", len(news))
+			}
+			if news[0].Name != step.title {
+				t.Errorf("Expected title %q, got %q", step.title, news[0].Name)
+			}
+			if news[0].Revisions != step.revisions {
+				t.Errorf("Expected %d revisions, got %d", step.revisions, news[0].Revisions)
+			}
+			if (news[0].UpdatedAt != nil) != step.edited {
+				t.Errorf("Expected edited=%v, got updated_at %v", step.edited, news[0].UpdatedAt)
+			}
+		})
+	}
+}
+
+// TestFeeds проверяет регистрацию лент и сохранение метаданных канала
+func TestFeeds(t *testing.T) {
+	ctx := context.Background()
+	errChan := make(chan error, 1)
+	dbInstance := New(ctx, testDB, errChan)
+	if dbInstance == nil {
+		t.Fatalf("Failed to initialize database: %v", <-errChan)
+	}
+	defer dbInstance.Close()
+
+	if _, err := dbInstance.Pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;"); err != nil {
+		t.Fatalf("Failed to truncate tables: %v", err)
+	}
+
+	feed, err := dbInstance.AddFeed(ctx, "http://example.com/rss")
+	if err != nil {
+		t.Fatalf("Failed to add feed: %v", err)
+	}
+	again, err := dbInstance.AddFeed(ctx, "http://example.com/rss")
+	if err != nil {
+		t.Fatalf("Failed to add feed twice: %v", err)
+	}
+	if again.ID != feed.ID || !feed.Enabled {
+		t.Errorf("Expected the same enabled feed, got %+v and %+v", feed, again)
+	}
+
+	feed.Title, feed.SiteLink, feed.Description, feed.Icon = "Example", "http://example.com/", "Example feed", "http://example.com/icon.png"
+	if err := dbInstance.UpdateFeedMeta(ctx, feed); err != nil {
+		t.Fatalf("Failed to update feed: %v", err)
+	}
+	err = dbInstance.StoreNews(ctx, []News{
+		{FeedID: feed.ID, Name: "News", Description: "Body", PublicationDate: time.Now(), Link: "http://example.com/1", GUID: "1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store news: %v", err)
+	}
+
+	feeds, err := dbInstance.Feeds(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list feeds: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].Title != "Example" || feeds[0].Icon != "http://example.com/icon.png" {
+		t.Errorf("Unexpected feeds: %+v", feeds)
+	}
+
+	page, err := dbInstance.News(ctx, NewsQuery{Limit: 1})
+	news := page.News
+	if err != nil {
+		t.Fatalf("Failed to list news: %v", err)
+	}
+	if len(news) != 1 || news[0].FeedID != feed.ID || news[0].FeedTitle != "Example" || news[0].Link != "http://example.com/1" {
+		t.Errorf("Unexpected news: %+v", news)
+	}
+}
+
+// TestFeedFailed проверяет учёт ошибок загрузки и автоматическое отключение ленты
+func TestFeedFailed(t *testing.T) {
+	ctx := context.Background()
+	errChan := make(chan error, 1)
+	dbInstance := New(ctx, testDB, errChan)
+	if dbInstance == nil {
+		t.Fatalf("Failed to initialize database: %v", <-errChan)
+	}
+	defer dbInstance.Close()
+
+	if _, err := dbInstance.Pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;"); err != nil {
+		t.Fatalf("Failed to truncate tables: %v", err)
+	}
+
+	feed, err := dbInstance.AddFeed(ctx, "http://example.com/broken")
+	if err != nil {
+		t.Fatalf("Failed to add feed: %v", err)
+	}
+
+	next := time.Now().Add(time.Hour)
+	for i := 1; i <= 3; i++ {
+		feed, err = dbInstance.FeedFailed(ctx, feed.ID, "connection refused", next, 3)
+		if err != nil {
+			t.Fatalf("FeedFailed() error = %v", err)
+		}
+		if feed.ConsecutiveFailures != i || feed.LastError != "connection refused" || feed.LastErrorAt == nil {
+			t.Errorf("Unexpected feed state after %d failures: %+v", i, feed)
+		}
+		if feed.Enabled != (i < 3) {
+			t.Errorf("Expected enabled = %v after %d failures, got %v", i < 3, i, feed.Enabled)
+		}
+	}
+
+	due, err := dbInstance.DueFeeds(ctx, next.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("DueFeeds() error = %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("Disabled feed must not be due: %+v", due)
+	}
+
+	// Повторное включение сбрасывает счётчик и ставит ленту в очередь сразу
+	enabled := true
+	feed, err = dbInstance.UpdateFeed(ctx, feed.ID, FeedPatch{Enabled: &enabled})
+	if err != nil {
+		t.Fatalf("UpdateFeed() error = %v", err)
+	}
+	if !feed.Enabled || feed.ConsecutiveFailures != 0 || feed.NextFetchAt != nil {
+		t.Errorf("Unexpected feed state after re-enabling: %+v", feed)
+	}
+}
+
+// TestSyncConfigFeeds проверяет сверку лент из настроек с базой
+func TestSyncConfigFeeds(t *testing.T) {
+	ctx := context.Background()
+	errChan := make(chan error, 1)
+	dbInstance := New(ctx, testDB, errChan)
+	if dbInstance == nil {
+		t.Fatalf("Failed to initialize database: %v", <-errChan)
+	}
+	defer dbInstance.Close()
+
+	if _, err := dbInstance.Pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;"); err != nil {
+		t.Fatalf("Failed to truncate tables: %v", err)
+	}
+	manual, err := dbInstance.AddFeed(ctx, "http://example.com/manual")
+	if err != nil {
+		t.Fatalf("Failed to add feed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		urls        []string
+		wantAdded   []string
+		wantRemoved []string
+		wantEnabled map[string]bool
+	}{
+		{
+			name:        "initial list",
+			urls:        []string{"http://example.com/a", "http://example.com/b"},
+			wantAdded:   []string{"http://example.com/a", "http://example.com/b"},
+			wantEnabled: map[string]bool{"http://example.com/a": true, "http://example.com/b": true},
+		},
+		{
+			name:        "same list is a no-op",
+			urls:        []string{"http://example.com/a", "http://example.com/b"},
+			wantEnabled: map[string]bool{"http://example.com/a": true, "http://example.com/b": true},
+		},
+		{
+			name:        "removed feed is disabled",
+			urls:        []string{"http://example.com/a"},
+			wantRemoved: []string{"http://example.com/b"},
+			wantEnabled: map[string]bool{"http://example.com/a": true, "http://example.com/b": false},
+		},
+		{
+			name:        "returned feed is enabled again",
+			urls:        []string{"http://example.com/a", "http://example.com/b"},
+			wantAdded:   []string{"http://example.com/b"},
+			wantEnabled: map[string]bool{"http://example.com/a": true, "http://example.com/b": true},
+		},
+		{
+			name:        "empty list",
+			wantRemoved: []string{"http://example.com/a", "http://example.com/b"},
+			wantEnabled: map[string]bool{"http://example.com/a": false, "http://example.com/b": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed, err := dbInstance.SyncConfigFeeds(ctx, tt.urls)
+			if err != nil {
+				t.Fatalf("SyncConfigFeeds() error = %v", err)
+			}
+			sort.Strings(added)
+			sort.Strings(removed)
+			if !reflect.DeepEqual(added, tt.wantAdded) || !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("SyncConfigFeeds() = %v, %v, want %v, %v", added, removed, tt.wantAdded, tt.wantRemoved)
+			}
+
+			feeds, err := dbInstance.Feeds(ctx)
+			if err != nil {
+				t.Fatalf("Feeds() error = %v", err)
+			}
+			for _, feed := range feeds {
+				if feed.ID == manual.ID {
+					// Лента из API сверкой не затрагивается
+					if !feed.Enabled || feed.InConfig {
+						t.Errorf("Manual feed changed: %+v", feed)
+					}
+					continue
+				}
+				if want, ok := tt.wantEnabled[feed.URL]; !ok || feed.Enabled != want {
+					t.Errorf("Feed %s enabled = %v, want %v", feed.URL, feed.Enabled, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrFeedExists  = errors.New("feed already exists")
+	ErrNoFeedPatch = errors.New("nothing to update")
+)
+
+// Feed - источник новостей и метаданные его канала.
+type Feed struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Title - название, заданное пользователем, либо заголовок канала.
+	Title       string    `json:"title"`
+	SiteLink    string    `json:"site_link"`
+	Description string    `json:"description"`
+	Icon        string    `json:"icon"`
+	AddedAt     time.Time `json:"added_at"`
+	Enabled     bool      `json:"enabled"`
+	// FetchInterval - интервал опроса в секундах, заданный пользователем,
+	// 0 - интервал выбирается по подсказкам канала.
+	FetchInterval int `json:"fetch_interval"`
+	// TTL - интервал обновления в секундах, объявленный самим каналом.
+	TTL int `json:"ttl"`
+	// SkipHours и SkipDays - битовые маски часов (UTC) и дней недели,
+	// в которые канал просит его не опрашивать.
+	SkipHours   int        `json:"-"`
+	SkipDays    int        `json:"-"`
+	NextFetchAt *time.Time `json:"next_fetch_at,omitempty"`
+	// ETag и LastModified - валидаторы последнего полного ответа для
+	// условных запросов, LastSize - его размер в байтах.
+	ETag         string `json:"-"`
+	LastModified string `json:"-"`
+	LastSize     int64  `json:"-"`
+	// BytesSaved - сколько байт не пришлось скачать благодаря ответам 304.
+	BytesSaved int64 `json:"bytes_saved"`
+	// ConsecutiveFailures - число ошибок загрузки подряд, LastError - текст
+	// последней из них. Лента, отключённая после ошибок, остаётся с
+	// enabled = false, пока её не включат снова.
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	// InConfig - лента перечислена в файле настроек. Такие ленты
+	// включаются и отключаются вместе с изменением списка rss.
+	InConfig bool `json:"in_config"`
+	// Category - папка ленты, пустая строка - вне папок.
+	Category string `json:"category"`
+}
+
+// FeedPatch - частичное изменение ленты. nil-поля не меняются.
+type FeedPatch struct {
+	// Title переименовывает ленту, пустая строка возвращает заголовок канала.
+	Title   *string `json:"title"`
+	Enabled *bool   `json:"enabled"`
+	// FetchInterval задаёт интервал опроса в секундах, 0 - автоматически.
+	FetchInterval *int `json:"fetch_interval"`
+	// Category переносит ленту в папку, пустая строка - из папки.
+	Category *string `json:"category"`
+}
+
+const feedColumns = `id, url, COALESCE(NULLIF(custom_title, ''), title), site_link, description, icon, added_at, enabled,
+	fetch_interval, ttl, skip_hours, skip_days, next_fetch_at, etag, last_modified, last_size, bytes_saved,
+	consecutive_failures, last_error, last_error_at, in_config, category`
+
+func scanFeed(row pgx.Row) (Feed, error) {
+	var feed Feed
+	err := row.Scan(&feed.ID, &feed.URL, &feed.Title, &feed.SiteLink, &feed.Description, &feed.Icon,
+		&feed.AddedAt, &feed.Enabled, &feed.FetchInterval, &feed.TTL, &feed.SkipHours, &feed.SkipDays, &feed.NextFetchAt,
+		&feed.ETag, &feed.LastModified, &feed.LastSize, &feed.BytesSaved, &feed.ConsecutiveFailures, &feed.LastError, &feed.LastErrorAt,
+		&feed.InConfig, &feed.Category)
+	return feed, err
+}
+
+// AddFeed регистрирует ленту по адресу. Если лента уже есть, возвращается
+// существующая запись.
+func (db *DB) AddFeed(ctx context.Context, url string) (Feed, error) {
+	feed, err := scanFeed(db.Pool.QueryRow(ctx, `
+		INSERT INTO feeds (url) VALUES ($1)
+		ON CONFLICT (url) DO UPDATE SET url = EXCLUDED.url
+		RETURNING `+feedColumns+`;`,
+		url))
+	if err != nil {
+		return Feed{}, fmt.Errorf("failed to add feed %s: %w", url, err)
+	}
+	return feed, nil
+}
+
+// SyncConfigFeeds приводит ленты из настроек к списку urls. Новые адреса
+// регистрируются, а ранее отключённые включаются снова; ленты, пропавшие из
+// списка, отключаются, но остаются в базе вместе с записями. Ленты, добавленные
+// через API, не затрагиваются. Возвращаются адреса включённых и отключённых лент.
+func (db *DB) SyncConfigFeeds(ctx context.Context, urls []string) (added, removed []string, err error) {
+	if urls == nil {
+		urls = []string{}
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	removed, err = collectURLs(tx.Query(ctx, `
+		UPDATE feeds SET in_config = false, enabled = false
+		WHERE in_config AND NOT (url = ANY($1::text[]))
+		RETURNING url;`,
+		urls))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to disable removed feeds: %w", err)
+	}
+	// Лента, которая уже была в настройках, сохраняет своё состояние:
+	// её могли поставить на паузу через API или отключить после ошибок
+	added, err = collectURLs(tx.Query(ctx, `
+		INSERT INTO feeds (url, in_config) SELECT DISTINCT unnest($1::text[]), true
+		ON CONFLICT (url) DO UPDATE SET
+			in_config = true,
+			enabled = true,
+			consecutive_failures = 0,
+			next_fetch_at = NULL
+		WHERE NOT feeds.in_config
+		RETURNING url;`,
+		urls))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to register config feeds: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit feeds: %w", err)
+	}
+	return added, removed, nil
+}
+
+func collectURLs(rows pgx.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
+
+// CreateFeed добавляет новую ленту вместе с уже известными метаданными.
+// Для существующего адреса возвращается ErrFeedExists.
+func (db *DB) CreateFeed(ctx context.Context, feed Feed) (Feed, error) {
+	created, err := scanFeed(db.Pool.QueryRow(ctx, `
+		INSERT INTO feeds (url, title, site_link, description, icon, enabled, category) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (url) DO NOTHING
+		RETURNING `+feedColumns+`;`,
+		feed.URL, feed.Title, feed.SiteLink, feed.Description, feed.Icon, feed.Enabled, feed.Category))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Feed{}, ErrFeedExists
+	}
+	if err != nil {
+		return Feed{}, fmt.Errorf("failed to create feed %s: %w", feed.URL, err)
+	}
+	return created, nil
+}
+
+// Feed возвращает ленту по идентификатору.
+func (db *DB) Feed(ctx context.Context, id int) (Feed, error) {
+	feed, err := scanFeed(db.Pool.QueryRow(ctx, `SELECT `+feedColumns+` FROM feeds WHERE id = $1;`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Feed{}, ErrNotFound
+	}
+	if err != nil {
+		return Feed{}, fmt.Errorf("failed to get feed %d: %w", id, err)
+	}
+	return feed, nil
+}
+
+// Feeds возвращает все ленты в порядке добавления.
+func (db *DB) Feeds(ctx context.Context) ([]Feed, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+feedColumns+` FROM feeds ORDER BY id;`)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Feed, 0)
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		result = append(result, feed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+// UpdateFeed применяет изменения пользователя: название, паузу, интервал
+// опроса и папку.
+func (db *DB) UpdateFeed(ctx context.Context, id int, patch FeedPatch) (Feed, error) {
+	if patch.Title == nil && patch.Enabled == nil && patch.FetchInterval == nil && patch.Category == nil {
+		return Feed{}, ErrNoFeedPatch
+	}
+	feed, err := scanFeed(db.Pool.QueryRow(ctx, `
+		UPDATE feeds SET
+			custom_title = COALESCE($2, custom_title),
+			enabled = COALESCE($3::boolean, enabled),
+			fetch_interval = COALESCE($4::integer, fetch_interval),
+			category = COALESCE($5, category),
+			-- включённая заново лента забывает прошлые ошибки и опрашивается сразу,
+			-- а более частый интервал начинает действовать, не дожидаясь следующей загрузки
+			next_fetch_at = CASE
+				WHEN $3::boolean AND consecutive_failures > 0 THEN NULL
+				WHEN $4::integer > 0 AND next_fetch_at > now() + make_interval(secs => $4::integer)
+					THEN now() + make_interval(secs => $4::integer)
+				ELSE next_fetch_at END,
+			consecutive_failures = CASE WHEN $3::boolean THEN 0 ELSE consecutive_failures END
+		WHERE id = $1
+		RETURNING `+feedColumns+`;`,
+		id, patch.Title, patch.Enabled, patch.FetchInterval, patch.Category))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Feed{}, ErrNotFound
+	}
+	if err != nil {
+		return Feed{}, fmt.Errorf("failed to update feed %d: %w", id, err)
+	}
+	return feed, nil
+}
+
+// DeleteFeed удаляет ленту вместе с её записями.
+func (db *DB) DeleteFeed(ctx context.Context, id int) error {
+	tag, err := db.Pool.Exec(ctx, "DELETE FROM feeds WHERE id = $1;", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete feed %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateFeedMeta сохраняет метаданные канала, полученные при разборе ленты,
+// включая его подсказки о расписании и валидаторы кэша.
+func (db *DB) UpdateFeedMeta(ctx context.Context, feed Feed) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE feeds SET title = $2, site_link = $3, description = $4, icon = $5,
+			ttl = $6, skip_hours = $7, skip_days = $8, etag = $9, last_modified = $10, last_size = $11
+		WHERE id = $1 AND (title, site_link, description, icon, ttl, skip_hours, skip_days, etag, last_modified, last_size)
+			IS DISTINCT FROM ($2::text, $3::text, $4::text, $5::text, $6::integer, $7::integer, $8::integer,
+			$9::text, $10::text, $11::bigint);`,
+		feed.ID, feed.Title, feed.SiteLink, feed.Description, feed.Icon, feed.TTL, feed.SkipHours, feed.SkipDays,
+		feed.ETag, feed.LastModified, feed.LastSize)
+	if err != nil {
+		return fmt.Errorf("failed to update feed %d: %w", feed.ID, err)
+	}
+	return nil
+}
+
+// DueFeeds возвращает включённые ленты, время опроса которых наступило.
+// Ленты, которые ещё ни разу не опрашивались, считаются просроченными.
+func (db *DB) DueFeeds(ctx context.Context, now time.Time) ([]Feed, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+feedColumns+` FROM feeds
+		WHERE enabled AND (next_fetch_at IS NULL OR next_fetch_at <= $1)
+		ORDER BY next_fetch_at NULLS FIRST, id;`,
+		now)
+	if err != nil {
+		// Поллер спрашивает базу каждый тик, так что смена пароля заметна здесь первой
+		return nil, unrecoverable(fmt.Errorf("query error: %w", err))
+	}
+	defer rows.Close()
+
+	result := make([]Feed, 0)
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		result = append(result, feed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+// ScheduleFeed после успешной загрузки сохраняет время следующего опроса,
+// чтобы после перезапуска ленты не запрашивались все разом, и сбрасывает
+// счётчик ошибок.
+func (db *DB) ScheduleFeed(ctx context.Context, id int, next time.Time) error {
+	_, err := db.Pool.Exec(ctx, "UPDATE feeds SET next_fetch_at = $2, consecutive_failures = 0 WHERE id = $1;", id, next)
+	if err != nil {
+		return fmt.Errorf("failed to schedule feed %d: %w", id, err)
+	}
+	return nil
+}
+
+// ShortenSchedule переносит на next опросы лент, которые идут с периодом по
+// умолчанию period (в секундах) и назначены позже next. Нужен, когда период
+// уменьшили без перезапуска. Ленты с ошибками и подсказками skipHours и
+// skipDays остаются со своим расписанием.
+func (db *DB) ShortenSchedule(ctx context.Context, next time.Time, period int) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE feeds SET next_fetch_at = $1
+		WHERE next_fetch_at > $1 AND fetch_interval = 0 AND ttl <= $2
+			AND consecutive_failures = 0 AND skip_hours = 0 AND skip_days = 0;`,
+		next, period)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule feeds: %w", err)
+	}
+	return nil
+}
+
+// MarkNotModified учитывает ответ 304: сэкономлен весь размер последнего
+// полного ответа.
+func (db *DB) MarkNotModified(ctx context.Context, id int) error {
+	_, err := db.Pool.Exec(ctx, "UPDATE feeds SET bytes_saved = bytes_saved + last_size WHERE id = $1;", id)
+	if err != nil {
+		return fmt.Errorf("failed to update feed %d: %w", id, err)
+	}
+	return nil
+}
+
+// FeedFailed записывает ошибку загрузки и время следующей попытки.
+// Когда ошибок подряд набирается maxFailures, лента отключается;
+// при maxFailures <= 0 лента не отключается никогда.
+func (db *DB) FeedFailed(ctx context.Context, id int, fetchErr string, next time.Time, maxFailures int) (Feed, error) {
+	feed, err := scanFeed(db.Pool.QueryRow(ctx, `
+		UPDATE feeds SET
+			consecutive_failures = consecutive_failures + 1,
+			last_error = $2,
+			last_error_at = now(),
+			next_fetch_at = $3,
+			enabled = enabled AND ($4::integer <= 0 OR consecutive_failures + 1 < $4::integer)
+		WHERE id = $1
+		RETURNING `+feedColumns+`;`,
+		id, fetchErr, next, maxFailures))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Feed{}, ErrNotFound
+	}
+	if err != nil {
+		return Feed{}, fmt.Errorf("failed to record error for feed %d: %w", id, err)
+	}
+	return feed, nil
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"goNews/pkg/rss/parser"
+)
+
+// upgradeLegacy доводит базу, созданную до появления миграций, до схемы
+// первой миграции. Её скрипт лишь досоздаёт недостающие таблицы, а колонки
+// старых таблиц переводятся кодом ниже. Все шаги и запись версии идут одной
+// транзакцией на соединении с блокировкой миграций: прерванное обновление
+// не оставляет базу наполовину переведённой и повторится при следующем запуске.
+func upgradeLegacy(ctx context.Context, conn *pgxpool.Conn, first Migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, first.up); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	if err := migratePublicationDate(ctx, tx); err != nil {
+		return fmt.Errorf("failed to migrate publication_date: %w", err)
+	}
+
+	if err := migrateGUID(ctx, tx); err != nil {
+		return fmt.Errorf("failed to migrate guid: %w", err)
+	}
+
+	if err := migrateRevisions(ctx, tx); err != nil {
+		return fmt.Errorf("failed to migrate revisions: %w", err)
+	}
+
+	if err := migrateFeeds(ctx, tx); err != nil {
+		return fmt.Errorf("failed to migrate feeds: %w", err)
+	}
+
+	if err := migrateFeedColumns(ctx, tx); err != nil {
+		return fmt.Errorf("failed to migrate feed columns: %w", err)
+	}
+
+	if err := dedupNews(ctx, tx); err != nil {
+		return fmt.Errorf("failed to remove duplicate news: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2);", first.Version, first.Name)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", first.Version, first.Name, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("Upgraded legacy schema to migration %d_%s\n", first.Version, first.Name)
+	return nil
+}
+
+// migratePublicationDate переводит publication_date из TEXT в TIMESTAMPTZ.
+// Строки в базе лежат в форматах лент (RFC1123, RFC822 с буквенными зонами),
+// которые Postgres не разбирает сам, поэтому даты пересчитываются в Go.
+func migratePublicationDate(ctx context.Context, tx pgx.Tx) error {
+	dataType, err := columnType(ctx, tx, "news", "publication_date")
+	if err != nil {
+		return err
+	}
+	if dataType != "text" {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, "ALTER TABLE news ADD COLUMN publication_time TIMESTAMPTZ;"); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT id, publication_date FROM news WHERE publication_date IS NOT NULL;")
+	if err != nil {
+		return err
+	}
+	batch := &pgx.Batch{}
+	for rows.Next() {
+		var id int
+		var raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		published, err := parser.ParseDate(raw)
+		if err != nil {
+			fmt.Printf("news %d: cannot parse publication date %q, leaving it empty\n", id, raw)
+			continue
+		}
+		batch.Queue("UPDATE news SET publication_time = $1 WHERE id = $2;", published, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if batch.Len() > 0 {
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		ALTER TABLE news DROP COLUMN publication_date;
+		ALTER TABLE news RENAME COLUMN publication_time TO publication_date;
+	`)
+	return err
+}
+
+// migrateGUID переводит уникальность записей с заголовка на guid.
+// Настоящие guid старых записей неизвестны, поэтому им достаётся заголовок,
+// который и был их идентичностью до миграции.
+func migrateGUID(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `
+		ALTER TABLE news ADD COLUMN IF NOT EXISTS guid TEXT;
+		UPDATE news SET guid = COALESCE(name, id::text) WHERE guid IS NULL;
+		ALTER TABLE news ALTER COLUMN guid SET NOT NULL;
+		ALTER TABLE news DROP CONSTRAINT IF EXISTS news_name_key;
+	`)
+	return err
+}
+
+// migrateRevisions добавляет хеш содержимого и историю правок записей.
+// У старых записей хеша нет: первое обновление лишь заполнит его,
+// не создавая ревизию.
+func migrateRevisions(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `
+		ALTER TABLE news ADD COLUMN IF NOT EXISTS content_hash TEXT;
+		ALTER TABLE news ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+		CREATE TABLE IF NOT EXISTS news_revisions (
+			id SERIAL PRIMARY KEY,
+			news_id INTEGER NOT NULL REFERENCES news (id) ON DELETE CASCADE,
+			name TEXT,
+			description TEXT,
+			content_hash TEXT,
+			replaced_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+	`)
+	return err
+}
+
+// migrateFeeds выносит источники в таблицу feeds. До миграции news.link
+// хранил адрес ленты: по нему заводятся записи feeds, а в link остаётся
+// ссылка на статью, если её удаётся восстановить из guid.
+func migrateFeeds(ctx context.Context, tx pgx.Tx) error {
+	dataType, err := columnType(ctx, tx, "news", "feed_id")
+	if err != nil {
+		return err
+	}
+	if dataType != "" {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		ALTER TABLE news ADD COLUMN feed_id INTEGER REFERENCES feeds (id) ON DELETE CASCADE;
+		INSERT INTO feeds (url) SELECT DISTINCT link FROM news WHERE link IS NOT NULL AND link <> ''
+			ON CONFLICT (url) DO NOTHING;
+		UPDATE news SET feed_id = feeds.id FROM feeds WHERE news.link = feeds.url;
+		UPDATE news SET link = CASE WHEN guid ~ '^https?://' THEN guid ELSE '' END;
+		DROP INDEX IF EXISTS news_link_guid_idx;
+	`)
+	return err
+}
+
+// migrateFeedColumns добавляет колонки feeds, появившиеся после создания таблицы.
+func migrateFeedColumns(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS custom_title TEXT NOT NULL DEFAULT '';
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS fetch_interval INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS ttl INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS skip_hours INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS skip_days INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS next_fetch_at TIMESTAMPTZ;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS etag TEXT NOT NULL DEFAULT '';
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_modified TEXT NOT NULL DEFAULT '';
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_size BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS bytes_saved BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_error_at TIMESTAMPTZ;
+		ALTER TABLE feeds ADD COLUMN IF NOT EXISTS in_config BOOLEAN NOT NULL DEFAULT false;
+	`)
+	return err
+}
+
+// dedupNews удаляет повторы записей одной ленты с тем же guid, оставляя
+// самую раннюю. В старой схеме guid не был уникальным, а заголовок,
+// ставший guid, мог повторяться, и без этого миграция 0002 не создаст
+// уникальный индекс (feed_id, guid). Ревизии удалённых записей уходят
+// вместе с ними.
+func dedupNews(ctx context.Context, tx pgx.Tx) error {
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM news n USING news d
+		WHERE n.feed_id = d.feed_id AND n.guid = d.guid AND n.id > d.id;
+	`)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		fmt.Printf("Removed %d duplicate news\n", tag.RowsAffected())
+	}
+	return nil
+}
+
+// querier - запросы, общие для соединения и транзакции.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// columnType возвращает тип колонки или пустую строку, если колонки нет.
+func columnType(ctx context.Context, q querier, table, column string) (string, error) {
+	var dataType string
+	err := q.QueryRow(ctx, `
+		SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2;
+	`, table, column).Scan(&dataType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	return dataType, nil
+}
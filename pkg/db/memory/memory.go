@@ -0,0 +1,533 @@
+// Package memory - хранилище новостей и лент в памяти процесса. Оно ведёт
+// себя так же, как Postgres-хранилище из пакета db, и нужно для тестов
+// и запуска без базы; при остановке всё содержимое теряется.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"goNews/pkg/db"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store реализует db.Store в памяти. Безопасен для одновременного использования.
+type Store struct {
+	mu         sync.Mutex
+	feeds      map[int]*feed
+	news       []*news
+	lastFeedID int
+	lastNewsID int
+}
+
+// feed хранит заголовок канала и заданный пользователем раздельно,
+// как колонки title и custom_title.
+type feed struct {
+	db.Feed
+	customTitle string
+}
+
+type news struct {
+	db.News
+	revisions int
+}
+
+var _ db.Store = (*Store)(nil)
+
+// New создаёт пустое хранилище.
+func New() *Store {
+	return &Store{feeds: make(map[int]*feed)}
+}
+
+func (f *feed) view() db.Feed {
+	result := f.Feed
+	if f.customTitle != "" {
+		result.Title = f.customTitle
+	}
+	return result
+}
+
+func (s *Store) News(ctx context.Context, q db.NewsQuery) (db.NewsPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	selected := make([]*news, 0, len(s.news))
+	for _, n := range s.news {
+		if q.After != nil && compare(n.News, *q.After) >= 0 || q.Before != nil && compare(n.News, *q.Before) <= 0 ||
+			!matches(q, n.News) {
+			continue
+		}
+		selected = append(selected, n)
+	}
+	// Для Before записи идут от старых к новым, как их выбрала бы база
+	sort.Slice(selected, func(i, j int) bool {
+		newer := compare(selected[i].News, db.CursorOf(selected[j].News)) > 0
+		return newer == (q.Before == nil)
+	})
+	if q.Limit+1 < len(selected) {
+		selected = selected[:q.Limit+1]
+	}
+
+	result := make([]db.News, 0, len(selected))
+	for _, n := range selected {
+		result = append(result, s.view(n))
+	}
+	return db.PageOf(q, result), nil
+}
+
+// matches проверяет фильтры q. Слово или фраза считается найденной,
+// если в заголовке или описании есть все её слова.
+func matches(q db.NewsQuery, n db.News) bool {
+	if len(q.FeedIDs) > 0 && !slices.Contains(q.FeedIDs, n.FeedID) {
+		return false
+	}
+	if !q.Since.IsZero() && n.PublicationDate.Before(q.Since) || !q.Until.IsZero() && !n.PublicationDate.Before(q.Until) {
+		return false
+	}
+	if q.Author != "" && !strings.EqualFold(q.Author, n.Author) {
+		return false
+	}
+	text := strings.ToLower(n.Name + " " + n.Description)
+	contains := func(keyword string) bool {
+		terms := db.SearchTerms(keyword)
+		for _, term := range terms {
+			if !strings.Contains(text, term) {
+				return false
+			}
+		}
+		return len(terms) > 0
+	}
+	for _, keyword := range q.Include {
+		if !contains(keyword) {
+			return false
+		}
+	}
+	for _, keyword := range q.Exclude {
+		if contains(keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare сравнивает положение записи с курсором: -1, если запись старше,
+// 1, если новее, и 0 для записи самого курсора.
+func compare(n db.News, c db.Cursor) int {
+	switch {
+	case n.PublicationDate.Before(c.PublishedAt):
+		return -1
+	case n.PublicationDate.After(c.PublishedAt):
+		return 1
+	case n.ID < c.ID:
+		return -1
+	case n.ID > c.ID:
+		return 1
+	}
+	return 0
+}
+
+// view возвращает запись в том виде, в каком её отдаёт база.
+func (s *Store) view(n *news) db.News {
+	item := n.News
+	item.ContentHash = ""
+	item.Revisions = n.revisions
+	if f, ok := s.feeds[item.FeedID]; ok {
+		item.FeedTitle = f.view().Title
+	}
+	return item
+}
+
+// StoreNews повторяет правила DB.StoreNews: запись определяется парой
+// (лента, guid), а при смене хеша содержимого считается новая ревизия.
+// Как и внешний ключ в Postgres, запись неизвестной ленты отклоняет всю пачку.
+func (s *Store) StoreNews(ctx context.Context, batch []db.News) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range batch {
+		if _, ok := s.feeds[item.FeedID]; !ok {
+			return fmt.Errorf("failed to store news %q: feed %d: %w", item.GUID, item.FeedID, db.ErrNotFound)
+		}
+	}
+
+	type key struct {
+		feedID int
+		guid   string
+	}
+	known := make(map[key]*news, len(s.news))
+	for _, n := range s.news {
+		known[key{n.FeedID, n.GUID}] = n
+	}
+
+	seen := make(map[key]bool, len(batch))
+	for _, item := range batch {
+		k := key{item.FeedID, item.GUID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		hash := item.ContentHash
+		if hash == "" {
+			hash = db.ContentHash(item.Name, item.Description)
+		}
+		if n, ok := known[k]; ok {
+			if n.ContentHash == hash {
+				continue
+			}
+			now := time.Now().UTC()
+			n.revisions++
+			n.Name, n.Description, n.Link, n.Author, n.ContentHash, n.UpdatedAt = item.Name, item.Description, item.Link, item.Author, hash, &now
+			continue
+		}
+
+		s.lastNewsID++
+		item.ID = s.lastNewsID
+		// Postgres хранит время с точностью до микросекунд, курсоры тоже
+		item.PublicationDate = item.PublicationDate.UTC().Truncate(time.Microsecond)
+		item.ContentHash = hash
+		item.FeedTitle, item.UpdatedAt, item.Revisions = "", nil, 0
+		n := &news{News: item}
+		s.news = append(s.news, n)
+		known[k] = n
+	}
+	return nil
+}
+
+// Search находит записи, в заголовке или описании которых встречаются все
+// слова запроса. Совпадение в заголовке весит вдвое больше, чем в описании.
+func (s *Store) Search(ctx context.Context, query string, limit int) ([]db.SearchResult, error) {
+	terms := db.SearchTerms(query)
+	result := make([]db.SearchResult, 0)
+	if len(terms) == 0 {
+		return result, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, n := range s.news {
+		name, description := strings.ToLower(n.Name), strings.ToLower(n.Description)
+		rank := 0
+		for _, term := range terms {
+			hits := 2*strings.Count(name, term) + strings.Count(description, term)
+			if hits == 0 {
+				rank = 0
+				break
+			}
+			rank += hits
+		}
+		if rank == 0 {
+			continue
+		}
+		item := s.view(n)
+		text := item.Description
+		if text == "" {
+			text = item.Name
+		}
+		result = append(result, db.SearchResult{News: item, Rank: float64(rank), Snippet: snippet(text, terms)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if !a.PublicationDate.Equal(b.PublicationDate) {
+			return a.PublicationDate.After(b.PublicationDate)
+		}
+		return a.ID > b.ID
+	})
+	if limit < len(result) {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// snippetWords - сколько слов текста попадает во фрагмент.
+const snippetWords = 30
+
+// snippet выделяет слова text, содержащие термы запроса, и оставляет
+// snippetWords слов вокруг первого совпадения.
+func snippet(text string, terms []string) string {
+	words := strings.Fields(text)
+	first := -1
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				words[i] = db.SnippetStart + word + db.SnippetStop
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+
+	start := first - snippetWords/3
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(words) {
+		end = len(words)
+	}
+	result := strings.Join(words[start:end], " ")
+	if start > 0 {
+		result = "… " + result
+	}
+	if end < len(words) {
+		result += " …"
+	}
+	return db.Highlight(result)
+}
+
+func (s *Store) AddFeed(ctx context.Context, url string) (db.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f := s.feedByURL(url); f != nil {
+		return f.view(), nil
+	}
+	return s.insertFeed(db.Feed{URL: url, Enabled: true}).view(), nil
+}
+
+// SyncConfigFeeds повторяет правила DB.SyncConfigFeeds.
+func (s *Store) SyncConfigFeeds(ctx context.Context, urls []string) (added, removed []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inList := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		inList[url] = true
+	}
+	for _, f := range s.sortedFeeds() {
+		if f.InConfig && !inList[f.URL] {
+			f.InConfig, f.Enabled = false, false
+			removed = append(removed, f.URL)
+		}
+	}
+	for _, url := range urls {
+		f := s.feedByURL(url)
+		switch {
+		case f == nil:
+			s.insertFeed(db.Feed{URL: url, Enabled: true, InConfig: true})
+		case !f.InConfig:
+			f.InConfig, f.Enabled, f.ConsecutiveFailures, f.NextFetchAt = true, true, 0, nil
+		default:
+			continue
+		}
+		added = append(added, url)
+	}
+	return added, removed, nil
+}
+
+func (s *Store) CreateFeed(ctx context.Context, f db.Feed) (db.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.feedByURL(f.URL) != nil {
+		return db.Feed{}, db.ErrFeedExists
+	}
+	return s.insertFeed(db.Feed{
+		URL:         f.URL,
+		Title:       f.Title,
+		SiteLink:    f.SiteLink,
+		Description: f.Description,
+		Icon:        f.Icon,
+		Enabled:     f.Enabled,
+		Category:    f.Category,
+	}).view(), nil
+}
+
+func (s *Store) Feed(ctx context.Context, id int) (db.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.feeds[id]
+	if !ok {
+		return db.Feed{}, db.ErrNotFound
+	}
+	return f.view(), nil
+}
+
+func (s *Store) Feeds(ctx context.Context) ([]db.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]db.Feed, 0, len(s.feeds))
+	for _, f := range s.sortedFeeds() {
+		result = append(result, f.view())
+	}
+	return result, nil
+}
+
+// UpdateFeed повторяет правила DB.UpdateFeed.
+func (s *Store) UpdateFeed(ctx context.Context, id int, patch db.FeedPatch) (db.Feed, error) {
+	if patch.Title == nil && patch.Enabled == nil && patch.FetchInterval == nil && patch.Category == nil {
+		return db.Feed{}, db.ErrNoFeedPatch
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.feeds[id]
+	if !ok {
+		return db.Feed{}, db.ErrNotFound
+	}
+	if patch.Title != nil {
+		f.customTitle = *patch.Title
+	}
+	if patch.Enabled != nil {
+		if *patch.Enabled && f.ConsecutiveFailures > 0 {
+			f.NextFetchAt = nil
+		}
+		if *patch.Enabled {
+			f.ConsecutiveFailures = 0
+		}
+		f.Enabled = *patch.Enabled
+	}
+	if patch.FetchInterval != nil {
+		every := time.Duration(*patch.FetchInterval) * time.Second
+		if next := time.Now().Add(every); every > 0 && f.NextFetchAt != nil && f.NextFetchAt.After(next) {
+			f.NextFetchAt = &next
+		}
+		f.FetchInterval = *patch.FetchInterval
+	}
+	if patch.Category != nil {
+		f.Category = *patch.Category
+	}
+	return f.view(), nil
+}
+
+func (s *Store) DeleteFeed(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.feeds[id]; !ok {
+		return db.ErrNotFound
+	}
+	delete(s.feeds, id)
+	kept := s.news[:0]
+	for _, n := range s.news {
+		if n.FeedID != id {
+			kept = append(kept, n)
+		}
+	}
+	s.news = kept
+	return nil
+}
+
+func (s *Store) UpdateFeedMeta(ctx context.Context, meta db.Feed) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.feeds[meta.ID]; ok {
+		f.Title, f.SiteLink, f.Description, f.Icon = meta.Title, meta.SiteLink, meta.Description, meta.Icon
+		f.TTL, f.SkipHours, f.SkipDays = meta.TTL, meta.SkipHours, meta.SkipDays
+		f.ETag, f.LastModified, f.LastSize = meta.ETag, meta.LastModified, meta.LastSize
+	}
+	return nil
+}
+
+func (s *Store) DueFeeds(ctx context.Context, now time.Time) ([]db.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]db.Feed, 0)
+	for _, f := range s.sortedFeeds() {
+		if f.Enabled && (f.NextFetchAt == nil || !f.NextFetchAt.After(now)) {
+			result = append(result, f.view())
+		}
+	}
+	// Ленты, которые ещё не опрашивались, идут первыми
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].NextFetchAt, result[j].NextFetchAt
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+	return result, nil
+}
+
+func (s *Store) ScheduleFeed(ctx context.Context, id int, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.feeds[id]; ok {
+		f.NextFetchAt, f.ConsecutiveFailures = &next, 0
+	}
+	return nil
+}
+
+func (s *Store) ShortenSchedule(ctx context.Context, next time.Time, period int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.feeds {
+		if f.NextFetchAt != nil && f.NextFetchAt.After(next) && f.FetchInterval == 0 && f.TTL <= period &&
+			f.ConsecutiveFailures == 0 && f.SkipHours == 0 && f.SkipDays == 0 {
+			f.NextFetchAt = &next
+		}
+	}
+	return nil
+}
+
+func (s *Store) MarkNotModified(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.feeds[id]; ok {
+		f.BytesSaved += f.LastSize
+	}
+	return nil
+}
+
+// FeedFailed повторяет правила DB.FeedFailed.
+func (s *Store) FeedFailed(ctx context.Context, id int, fetchErr string, next time.Time, maxFailures int) (db.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.feeds[id]
+	if !ok {
+		return db.Feed{}, db.ErrNotFound
+	}
+	now := time.Now()
+	f.ConsecutiveFailures++
+	f.LastError, f.LastErrorAt, f.NextFetchAt = fetchErr, &now, &next
+	if maxFailures > 0 && f.ConsecutiveFailures >= maxFailures {
+		f.Enabled = false
+	}
+	return f.view(), nil
+}
+
+func (s *Store) Close() {}
+
+func (s *Store) insertFeed(f db.Feed) *feed {
+	s.lastFeedID++
+	f.ID = s.lastFeedID
+	f.AddedAt = time.Now()
+	stored := &feed{Feed: f}
+	s.feeds[f.ID] = stored
+	return stored
+}
+
+func (s *Store) feedByURL(url string) *feed {
+	for _, f := range s.feeds {
+		if f.URL == url {
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *Store) sortedFeeds() []*feed {
+	result := make([]*feed, 0, len(s.feeds))
+	for _, f := range s.feeds {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
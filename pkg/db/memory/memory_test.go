@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"testing"
+
+	"goNews/pkg/db"
+	"goNews/pkg/db/storetest"
+)
+
+// TestStore прогоняет общие проверки хранилищ
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) db.Store { return New() }, storetest.Options{
+		NoStemming:  "memory store matches word prefixes only",
+		NoWebSearch: "memory store requires all query words, without query operators",
+	})
+}
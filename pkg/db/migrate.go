@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Схема базы задаётся только миграциями из каталога migrations. Файлы
+// называются NNNN_описание.up.sql и NNNN_описание.down.sql; применённые
+// версии записываются в schema_migrations.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock - ключ advisory-блокировки, под которой применяются миграции,
+// чтобы одновременно запущенные экземпляры не меняли схему вместе.
+const migrationLock = 0x676f4e657773
+
+// Migration - версия схемы. AppliedAt пуст, если миграция ещё не применена.
+type Migration struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+
+	up, down string
+}
+
+// loadMigrations читает миграции из fsys и проверяет, что у каждой версии
+// есть оба скрипта, а номера не повторяются.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+		base, direction := strings.TrimSuffix(file, ".up.sql"), "up"
+		if base == file {
+			base, direction = strings.TrimSuffix(file, ".down.sql"), "down"
+		}
+		number, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if base == file || !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+
+		script, err := fs.ReadFile(fsys, path.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.up = string(script)
+		} else {
+			m.down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down scripts", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate применяет все ещё не применённые миграции.
+func (db *DB) Migrate(ctx context.Context) error {
+	return db.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []Migration, applied map[int]time.Time) error {
+		if len(applied) == 0 {
+			legacy, err := columnType(ctx, conn, "news", "id")
+			if err != nil {
+				return err
+			}
+			if legacy != "" {
+				// База создана до появления миграций: первая миграция
+				// досоздаёт таблицы, а старые колонки доводятся кодом
+				if err := upgradeLegacy(ctx, conn, migrations[0]); err != nil {
+					return err
+				}
+				applied[migrations[0].Version] = time.Now()
+			}
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			err := runMigration(ctx, conn, m.up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2);", m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			fmt.Printf("Applied migration %d_%s\n", m.Version, m.Name)
+		}
+		return nil
+	})
+}
+
+// MigrateDown откатывает steps последних применённых миграций.
+func (db *DB) MigrateDown(ctx context.Context, steps int) error {
+	return db.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []Migration, applied map[int]time.Time) error {
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			err := runMigration(ctx, conn, m.down, "DELETE FROM schema_migrations WHERE version = $1;", m.Version)
+			if err != nil {
+				return fmt.Errorf("rollback of migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			fmt.Printf("Rolled back migration %d_%s\n", m.Version, m.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// Migrations возвращает все известные миграции с отметкой о применении.
+func (db *DB) Migrations(ctx context.Context) ([]Migration, error) {
+	var result []Migration
+	err := db.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []Migration, applied map[int]time.Time) error {
+		for _, m := range migrations {
+			if at, ok := applied[m.Version]; ok {
+				m.AppliedAt = &at
+			}
+			result = append(result, m)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// withMigrationLock берёт блокировку миграций на отдельном соединении,
+// создаёт schema_migrations и передаёт fn список миграций и уже применённые версии.
+func (db *DB) withMigrationLock(ctx context.Context,
+	fn func(conn *pgxpool.Conn, migrations []Migration, applied map[int]time.Time) error) error {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1);", int64(migrationLock)); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1);", int64(migrationLock))
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[version] = at
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	return fn(conn, migrations, applied)
+}
+
+// runMigration выполняет скрипт и запись в schema_migrations в одной транзакции.
+func runMigration(ctx context.Context, conn *pgxpool.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Без параметров pgx отправляет скрипт целиком, со всеми командами
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
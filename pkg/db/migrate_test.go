@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// TestLoadMigrations проверяет разбор и проверку файлов миграций
+func TestLoadMigrations(t *testing.T) {
+	script := &fstest.MapFile{Data: []byte("SELECT 1;")}
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		expected []int
+		wantErr  bool
+	}{
+		{
+			name: "sorted by version",
+			files: fstest.MapFS{
+				"m/0010_later.up.sql":   script,
+				"m/0010_later.down.sql": script,
+				"m/0002_first.up.sql":   script,
+				"m/0002_first.down.sql": script,
+			},
+			expected: []int{2, 10},
+		},
+		{
+			name: "missing down script",
+			files: fstest.MapFS{
+				"m/0001_tables.up.sql": script,
+			},
+			wantErr: true,
+		},
+		{
+			name: "no version",
+			files: fstest.MapFS{
+				"m/tables.up.sql":   script,
+				"m/tables.down.sql": script,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown suffix",
+			files: fstest.MapFS{
+				"m/0001_tables.sql": script,
+			},
+			wantErr: true,
+		},
+		{
+			name: "different names",
+			files: fstest.MapFS{
+				"m/0001_tables.up.sql":  script,
+				"m/0001_other.down.sql": script,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.files, "m")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error, got %+v", migrations)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadMigrations failed: %v", err)
+			}
+			if len(migrations) != len(tt.expected) {
+				t.Fatalf("Expected %d migrations, got %d", len(tt.expected), len(migrations))
+			}
+			for i, m := range migrations {
+				if m.Version != tt.expected[i] || m.up == "" || m.down == "" {
+					t.Errorf("Migration %d: got %+v, expected version %d with both scripts", i, m, tt.expected[i])
+				}
+			}
+		})
+	}
+
+	// Встроенные миграции тоже должны проходить проверку
+	if _, err := loadMigrations(migrationFiles, "migrations"); err != nil {
+		t.Errorf("Embedded migrations are invalid: %v", err)
+	}
+}
+
+// TestMigrations проверяет откат и повторное применение последней миграции
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	errChan := make(chan error, 1)
+	dbInstance := New(ctx, testDB, errChan)
+	if dbInstance == nil {
+		t.Fatalf("Failed to initialize database: %v", <-errChan)
+	}
+	defer dbInstance.Close()
+
+	pending := func() int {
+		migrations, err := dbInstance.Migrations(ctx)
+		if err != nil {
+			t.Fatalf("Migrations failed: %v", err)
+		}
+		count := 0
+		for _, m := range migrations {
+			if m.AppliedAt == nil {
+				count++
+			}
+		}
+		return count
+	}
+
+	if n := pending(); n != 0 {
+		t.Fatalf("Expected all migrations applied after New, %d pending", n)
+	}
+	if err := dbInstance.MigrateDown(ctx, 1); err != nil {
+		t.Fatalf("MigrateDown failed: %v", err)
+	}
+	if n := pending(); n != 1 {
+		t.Fatalf("Expected 1 pending migration after rollback, got %d", n)
+	}
+	if err := dbInstance.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if n := pending(); n != 0 {
+		t.Fatalf("Expected all migrations applied again, %d pending", n)
+	}
+}
+
+// TestLegacyUpgrade проверяет перевод базы, созданной до миграций, с
+// повторами записей. База строится в отдельной схеме, чтобы не задеть
+// таблицы остальных тестов.
+func TestLegacyUpgrade(t *testing.T) {
+	ctx := context.Background()
+	const schema = "legacy_test"
+	poolConf, err := pgxpool.ParseConfig(testDB.ConnString())
+	if err != nil {
+		t.Fatalf("Failed to parse connection string: %v", err)
+	}
+	admin, err := pgxpool.ConnectConfig(ctx, poolConf)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer admin.Close()
+	if _, err := admin.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE; CREATE SCHEMA "+schema+";"); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	defer admin.Exec(ctx, "DROP SCHEMA IF EXISTS "+schema+" CASCADE;")
+
+	poolConf.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.ConnectConfig(ctx, poolConf)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	legacy := &DB{Pool: pool}
+	defer legacy.Close()
+
+	// Старая схема: заголовок станет guid, и в одной ленте он повторяется
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE news (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			description TEXT,
+			publication_date TEXT,
+			link TEXT
+		);
+		INSERT INTO news (name, description, publication_date, link) VALUES
+			('Go 1.22', 'first', 'Tue, 06 Feb 2024 10:00:00 GMT', 'https://go.dev/blog/feed.atom'),
+			('Go 1.22', 'repost', 'Tue, 06 Feb 2024 12:00:00 GMT', 'https://go.dev/blog/feed.atom'),
+			('Go 1.22', 'other feed', 'Wed, 07 Feb 2024 10:00:00 GMT', 'https://habr.com/ru/rss/all/');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create legacy fixture: %v", err)
+	}
+
+	if err := legacy.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	rows, err := pool.Query(ctx, "SELECT description FROM news ORDER BY id;")
+	if err != nil {
+		t.Fatalf("Failed to query news: %v", err)
+	}
+	defer rows.Close()
+	var descriptions []string
+	for rows.Next() {
+		var description string
+		if err := rows.Scan(&description); err != nil {
+			t.Fatalf("Failed to scan news: %v", err)
+		}
+		descriptions = append(descriptions, description)
+	}
+	if expected := []string{"first", "other feed"}; !reflect.DeepEqual(descriptions, expected) {
+		t.Errorf("News after upgrade = %v, expected %v", descriptions, expected)
+	}
+
+	migrations, err := legacy.Migrations(ctx)
+	if err != nil {
+		t.Fatalf("Migrations failed: %v", err)
+	}
+	for _, m := range migrations {
+		if m.AppliedAt == nil {
+			t.Errorf("Migration %d_%s is not applied", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS news_revisions;
+DROP TABLE IF EXISTS news;
+DROP TABLE IF EXISTS feeds;
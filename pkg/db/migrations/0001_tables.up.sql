@@ -0,0 +1,47 @@
+-- Таблицы создаются с IF NOT EXISTS: в базах, созданных до появления
+-- миграций, часть таблиц уже есть и доводится до этой схемы отдельно.
+CREATE TABLE IF NOT EXISTS feeds (
+	id SERIAL PRIMARY KEY,
+	url TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL DEFAULT '',
+	custom_title TEXT NOT NULL DEFAULT '',
+	site_link TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	icon TEXT NOT NULL DEFAULT '',
+	added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	enabled BOOLEAN NOT NULL DEFAULT true,
+	fetch_interval INTEGER NOT NULL DEFAULT 0,
+	ttl INTEGER NOT NULL DEFAULT 0,
+	skip_hours INTEGER NOT NULL DEFAULT 0,
+	skip_days INTEGER NOT NULL DEFAULT 0,
+	next_fetch_at TIMESTAMPTZ,
+	etag TEXT NOT NULL DEFAULT '',
+	last_modified TEXT NOT NULL DEFAULT '',
+	last_size BIGINT NOT NULL DEFAULT 0,
+	bytes_saved BIGINT NOT NULL DEFAULT 0,
+	consecutive_failures INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	last_error_at TIMESTAMPTZ,
+	in_config BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS news (
+	id SERIAL PRIMARY KEY,
+	feed_id INTEGER REFERENCES feeds (id) ON DELETE CASCADE,
+	name TEXT,
+	description TEXT,
+	publication_date TIMESTAMPTZ,
+	link TEXT,
+	guid TEXT NOT NULL,
+	content_hash TEXT,
+	updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS news_revisions (
+	id SERIAL PRIMARY KEY,
+	news_id INTEGER NOT NULL REFERENCES news (id) ON DELETE CASCADE,
+	name TEXT,
+	description TEXT,
+	content_hash TEXT,
+	replaced_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
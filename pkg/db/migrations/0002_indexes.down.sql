@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS news_revisions_news_id_idx;
+DROP INDEX IF EXISTS news_feed_guid_idx;
+DROP INDEX IF EXISTS news_publication_date_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS news_publication_date_idx ON news (publication_date DESC, id DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS news_feed_guid_idx ON news (feed_id, guid);
+CREATE INDEX IF NOT EXISTS news_revisions_news_id_idx ON news_revisions (news_id);
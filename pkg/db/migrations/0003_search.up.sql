@@ -0,0 +1,10 @@
+-- Поиск по заголовку и описанию. Ленты бывают русские и английские,
+-- поэтому каждое поле разбирается обеими конфигурациями; заголовок весит больше.
+ALTER TABLE news ADD COLUMN search tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('russian', COALESCE(name, '')), 'A') ||
+	setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+	setweight(to_tsvector('russian', COALESCE(description, '')), 'B') ||
+	setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+) STORED;
+
+CREATE INDEX news_search_idx ON news USING GIN (search);
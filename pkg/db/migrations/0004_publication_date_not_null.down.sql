@@ -0,0 +1 @@
+ALTER TABLE news ALTER COLUMN publication_date DROP NOT NULL;
@@ -0,0 +1,5 @@
+-- Страницы отсчитываются от пары (publication_date, id), и NULL в ней
+-- выпадал бы из сравнения. Пустую дату сохраняет только перенос старых
+-- текстовых дат; ей соответствует нулевое время Go, как у записей без даты.
+UPDATE news SET publication_date = '0001-01-01 00:00:00+00' WHERE publication_date IS NULL;
+ALTER TABLE news ALTER COLUMN publication_date SET NOT NULL;
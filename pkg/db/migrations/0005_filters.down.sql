@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS news_author_idx;
+DROP INDEX IF EXISTS news_feed_publication_date_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS author;
@@ -0,0 +1,6 @@
+-- Автор записи и индексы для фильтров списка: по лентам с порядком страниц
+-- и по автору без учёта регистра. Слова ищутся по индексу news_search_idx.
+ALTER TABLE news ADD COLUMN author TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX news_feed_publication_date_idx ON news (feed_id, publication_date DESC, id DESC);
+CREATE INDEX news_author_idx ON news (lower(author));
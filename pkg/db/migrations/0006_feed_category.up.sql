@@ -0,0 +1,3 @@
+-- Папка ленты, как в OPML. Папки не хранятся отдельно: папка существует,
+-- пока в ней есть ленты.
+ALTER TABLE feeds ADD COLUMN category TEXT NOT NULL DEFAULT '';
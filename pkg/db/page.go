@@ -0,0 +1,110 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidCursor - курсор страницы повреждён или создан не этим сервером.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// NewsQuery - какие записи выбрать. Без курсоров выбираются самые новые;
+// с After - записи старше курсора, с Before - новее него. Пустые фильтры
+// не применяются, заданные должны выполняться все сразу.
+type NewsQuery struct {
+	Limit  int
+	After  *Cursor
+	Before *Cursor
+
+	// FeedIDs - записи только этих лент.
+	FeedIDs []int
+	// Since и Until ограничивают дату публикации: Since включительно, Until - нет.
+	Since time.Time
+	Until time.Time
+	// Include - слова или фразы, каждая из которых должна встретиться
+	// в заголовке или описании; Exclude - ни одна из которых не должна.
+	Include []string
+	Exclude []string
+	// Author - автор записи без учёта регистра.
+	Author string
+}
+
+// NewsPage - страница записей, новые первыми. Next ведёт к более старым
+// записям, Prev - к более новым; курсор пуст, если в ту сторону записей нет.
+type NewsPage struct {
+	News []News
+	Next *Cursor
+	Prev *Cursor
+}
+
+// Cursor - запись на краю страницы. Записи упорядочены по паре
+// (дата публикации, id), поэтому страницы не съезжают, когда
+// появляются новые записи.
+type Cursor struct {
+	PublishedAt time.Time
+	ID          int
+}
+
+// CursorOf возвращает курсор, указывающий на запись n.
+func CursorOf(n News) Cursor {
+	return Cursor{PublishedAt: n.PublicationDate, ID: n.ID}
+}
+
+// String кодирует курсор в непрозрачную строку для URL. Время хранится
+// в микросекундах, как в базе.
+func (c Cursor) String() string {
+	raw := fmt.Sprintf("%d.%d", c.PublishedAt.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor разбирает строку, полученную от Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var micro int64
+	var id int
+	if n, err := fmt.Sscanf(string(raw), "%d.%d", &micro, &id); err != nil || n != 2 || id <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{PublishedAt: time.UnixMicro(micro).UTC(), ID: id}, nil
+}
+
+// PageOf собирает страницу из записей, выбранных хранилищем по q.
+// Хранилище запрашивает на одну запись больше Limit, чтобы узнать, есть
+// ли записи дальше, а для Before - в порядке от старых к новым.
+func PageOf(q NewsQuery, rows []News) NewsPage {
+	more := len(rows) > q.Limit
+	if more {
+		rows = rows[:q.Limit]
+	}
+	if q.Before != nil {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	page := NewsPage{News: rows}
+	if len(rows) == 0 {
+		return page
+	}
+	first, last := CursorOf(rows[0]), CursorOf(rows[len(rows)-1])
+	// Запись самого курсора лежит по другую сторону страницы
+	if q.Before != nil {
+		page.Next = &last
+		if more {
+			page.Prev = &first
+		}
+	} else {
+		if more {
+			page.Next = &last
+		}
+		if q.After != nil {
+			page.Prev = &first
+		}
+	}
+	return page
+}
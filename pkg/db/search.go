@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SearchResult - запись, найденная полнотекстовым поиском.
+type SearchResult struct {
+	News
+	// Rank - релевантность, чем больше, тем лучше. Сравнима только
+	// в пределах одной выдачи.
+	Rank float64 `json:"rank"`
+	// Snippet - фрагмент описания (или заголовка, если описания нет) в HTML:
+	// текст экранирован, найденные слова выделены тегами <b>.
+	Snippet string `json:"snippet"`
+}
+
+// Разметка найденных слов во фрагментах.
+const (
+	HighlightStart = "<b>"
+	HighlightStop  = "</b>"
+)
+
+// Метки найденных слов, которые ставит движок поиска. Текст записей -
+// это текст, а не HTML, поэтому теги подставляет Highlight уже после
+// экранирования.
+const (
+	SnippetStart = "\x02"
+	SnippetStop  = "\x03"
+)
+
+// Highlight превращает фрагмент с метками SnippetStart и SnippetStop в HTML:
+// экранирует текст и заменяет метки тегами выделения.
+func Highlight(snippet string) string {
+	return strings.NewReplacer(SnippetStart, HighlightStart, SnippetStop, HighlightStop).
+		Replace(html.EscapeString(snippet))
+}
+
+// SearchTerms разбивает запрос на слова в нижнем регистре. Хранилища без
+// полнотекстового движка Postgres ищут записи, содержащие все эти слова.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Search ищет записи по заголовку и описанию. Запрос понимает синтаксис
+// websearch_to_tsquery: фразы в кавычках, or и исключение словами с минусом.
+// Слова приводятся к основе по правилам русского и английского языков.
+// Выдача упорядочена по релевантности, затем новые первыми.
+func (db *DB) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	// ts_headline дорогой, поэтому фрагменты строятся только для
+	// отобранных записей. Конфигурация russian разбирает латиницу
+	// английским стеммером, так что подсвечиваются слова обоих языков.
+	rows, err := db.Pool.Query(ctx, `
+		WITH q AS (
+			SELECT websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1) AS query
+		)
+		SELECT found.id, found.feed_id, found.feed_title, found.name, found.description, found.publication_date,
+			found.link, found.author, found.guid, found.updated_at, found.revisions, found.rank,
+			ts_headline('russian', COALESCE(NULLIF(found.description, ''), found.name, ''), q.query,
+				'StartSel="`+SnippetStart+`", StopSel="`+SnippetStop+`", MaxWords=35, MinWords=15, MaxFragments=2')
+		FROM q, (
+			SELECT n.id, COALESCE(n.feed_id, 0) AS feed_id, COALESCE(NULLIF(f.custom_title, ''), f.title, '') AS feed_title,
+				n.name, n.description, n.publication_date, n.link, n.author, n.guid, n.updated_at,
+				(SELECT count(*) FROM news_revisions r WHERE r.news_id = n.id) AS revisions,
+				ts_rank(n.search, q.query)::float8 AS rank
+			FROM news n
+			CROSS JOIN q
+			LEFT JOIN feeds f ON f.id = n.feed_id
+			WHERE n.search @@ q.query
+			ORDER BY rank DESC, n.publication_date DESC NULLS LAST, n.id DESC
+			LIMIT $2
+		) AS found
+		ORDER BY found.rank DESC, found.publication_date DESC NULLS LAST, found.id DESC;`,
+		query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search error: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]SearchResult, 0)
+	for rows.Next() {
+		var found SearchResult
+		var published *time.Time
+		if err := rows.Scan(&found.ID, &found.FeedID, &found.FeedTitle, &found.Name, &found.Description, &published,
+			&found.Link, &found.Author, &found.GUID, &found.UpdatedAt, &found.Revisions, &found.Rank, &found.Snippet); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		if published != nil {
+			found.PublicationDate = published.UTC()
+		}
+		if found.UpdatedAt != nil {
+			updated := found.UpdatedAt.UTC()
+			found.UpdatedAt = &updated
+		}
+		found.Snippet = Highlight(found.Snippet)
+		result = append(result, found)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,285 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goNews/pkg/db"
+	"time"
+)
+
+const feedColumns = `id, url, COALESCE(NULLIF(custom_title, ''), title), site_link, description, icon, added_at, enabled,
+	fetch_interval, ttl, skip_hours, skip_days, next_fetch_at, etag, last_modified, last_size, bytes_saved,
+	consecutive_failures, last_error, last_error_at, in_config, category`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFeed(row scanner) (db.Feed, error) {
+	var feed db.Feed
+	var addedAt int64
+	var nextFetchAt, lastErrorAt sql.NullInt64
+	err := row.Scan(&feed.ID, &feed.URL, &feed.Title, &feed.SiteLink, &feed.Description, &feed.Icon,
+		&addedAt, &feed.Enabled, &feed.FetchInterval, &feed.TTL, &feed.SkipHours, &feed.SkipDays, &nextFetchAt,
+		&feed.ETag, &feed.LastModified, &feed.LastSize, &feed.BytesSaved, &feed.ConsecutiveFailures, &feed.LastError,
+		&lastErrorAt, &feed.InConfig, &feed.Category)
+	feed.AddedAt = timeOf(addedAt)
+	feed.NextFetchAt, feed.LastErrorAt = timePtr(nextFetchAt), timePtr(lastErrorAt)
+	return feed, err
+}
+
+func scanFeeds(rows *sql.Rows, err error) ([]db.Feed, error) {
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]db.Feed, 0)
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		result = append(result, feed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return result, nil
+}
+
+func (s *Store) AddFeed(ctx context.Context, url string) (db.Feed, error) {
+	feed, err := scanFeed(s.DB.QueryRowContext(ctx, `
+		INSERT INTO feeds (url, added_at) VALUES (?1, ?2)
+		ON CONFLICT (url) DO UPDATE SET url = excluded.url
+		RETURNING `+feedColumns+`;`,
+		url, unixTime(time.Now())))
+	if err != nil {
+		return db.Feed{}, fmt.Errorf("failed to add feed %s: %w", url, err)
+	}
+	return feed, nil
+}
+
+// SyncConfigFeeds повторяет правила DB.SyncConfigFeeds.
+func (s *Store) SyncConfigFeeds(ctx context.Context, urls []string) (added, removed []string, err error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inList := make(map[string]bool, len(urls))
+	var unique []string
+	for _, url := range urls {
+		if !inList[url] {
+			inList[url] = true
+			unique = append(unique, url)
+		}
+	}
+	configured, err := collectURLs(tx.QueryContext(ctx, "SELECT url FROM feeds WHERE in_config ORDER BY id;"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list config feeds: %w", err)
+	}
+	for _, url := range configured {
+		if inList[url] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE feeds SET in_config = 0, enabled = 0 WHERE url = ?;", url); err != nil {
+			return nil, nil, fmt.Errorf("failed to disable removed feeds: %w", err)
+		}
+		removed = append(removed, url)
+	}
+
+	// Лента, которая уже была в настройках, сохраняет своё состояние:
+	// её могли поставить на паузу через API или отключить после ошибок
+	now := unixTime(time.Now())
+	for _, url := range unique {
+		var registered string
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO feeds (url, in_config, added_at) VALUES (?1, 1, ?2)
+			ON CONFLICT (url) DO UPDATE SET
+				in_config = 1,
+				enabled = 1,
+				consecutive_failures = 0,
+				next_fetch_at = NULL
+			WHERE NOT feeds.in_config
+			RETURNING url;`,
+			url, now).Scan(&registered)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to register config feeds: %w", err)
+		}
+		added = append(added, registered)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit feeds: %w", err)
+	}
+	return added, removed, nil
+}
+
+func collectURLs(rows *sql.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
+
+func (s *Store) CreateFeed(ctx context.Context, feed db.Feed) (db.Feed, error) {
+	created, err := scanFeed(s.DB.QueryRowContext(ctx, `
+		INSERT INTO feeds (url, title, site_link, description, icon, enabled, added_at, category)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+		ON CONFLICT (url) DO NOTHING
+		RETURNING `+feedColumns+`;`,
+		feed.URL, feed.Title, feed.SiteLink, feed.Description, feed.Icon, feed.Enabled, unixTime(time.Now()), feed.Category))
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.Feed{}, db.ErrFeedExists
+	}
+	if err != nil {
+		return db.Feed{}, fmt.Errorf("failed to create feed %s: %w", feed.URL, err)
+	}
+	return created, nil
+}
+
+func (s *Store) Feed(ctx context.Context, id int) (db.Feed, error) {
+	feed, err := scanFeed(s.DB.QueryRowContext(ctx, `SELECT `+feedColumns+` FROM feeds WHERE id = ?;`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.Feed{}, db.ErrNotFound
+	}
+	if err != nil {
+		return db.Feed{}, fmt.Errorf("failed to get feed %d: %w", id, err)
+	}
+	return feed, nil
+}
+
+func (s *Store) Feeds(ctx context.Context) ([]db.Feed, error) {
+	return scanFeeds(s.DB.QueryContext(ctx, `SELECT `+feedColumns+` FROM feeds ORDER BY id;`))
+}
+
+// UpdateFeed повторяет правила DB.UpdateFeed.
+func (s *Store) UpdateFeed(ctx context.Context, id int, patch db.FeedPatch) (db.Feed, error) {
+	if patch.Title == nil && patch.Enabled == nil && patch.FetchInterval == nil && patch.Category == nil {
+		return db.Feed{}, db.ErrNoFeedPatch
+	}
+	var sooner sql.NullInt64
+	if patch.FetchInterval != nil {
+		sooner = sql.NullInt64{Int64: unixTime(time.Now().Add(time.Duration(*patch.FetchInterval) * time.Second)), Valid: true}
+	}
+	feed, err := scanFeed(s.DB.QueryRowContext(ctx, `
+		UPDATE feeds SET
+			custom_title = COALESCE(?2, custom_title),
+			enabled = COALESCE(?3, enabled),
+			fetch_interval = COALESCE(?4, fetch_interval),
+			category = COALESCE(?6, category),
+			next_fetch_at = CASE
+				WHEN ?3 AND consecutive_failures > 0 THEN NULL
+				WHEN ?4 > 0 AND next_fetch_at > ?5 THEN ?5
+				ELSE next_fetch_at END,
+			consecutive_failures = CASE WHEN ?3 THEN 0 ELSE consecutive_failures END
+		WHERE id = ?1
+		RETURNING `+feedColumns+`;`,
+		id, patch.Title, patch.Enabled, patch.FetchInterval, sooner, patch.Category))
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.Feed{}, db.ErrNotFound
+	}
+	if err != nil {
+		return db.Feed{}, fmt.Errorf("failed to update feed %d: %w", id, err)
+	}
+	return feed, nil
+}
+
+func (s *Store) DeleteFeed(ctx context.Context, id int) error {
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM feeds WHERE id = ?;", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete feed %d: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return db.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) UpdateFeedMeta(ctx context.Context, feed db.Feed) error {
+	_, err := s.DB.ExecContext(ctx, `
+		UPDATE feeds SET title = ?2, site_link = ?3, description = ?4, icon = ?5,
+			ttl = ?6, skip_hours = ?7, skip_days = ?8, etag = ?9, last_modified = ?10, last_size = ?11
+		WHERE id = ?1;`,
+		feed.ID, feed.Title, feed.SiteLink, feed.Description, feed.Icon, feed.TTL, feed.SkipHours, feed.SkipDays,
+		feed.ETag, feed.LastModified, feed.LastSize)
+	if err != nil {
+		return fmt.Errorf("failed to update feed %d: %w", feed.ID, err)
+	}
+	return nil
+}
+
+func (s *Store) DueFeeds(ctx context.Context, now time.Time) ([]db.Feed, error) {
+	return scanFeeds(s.DB.QueryContext(ctx, `
+		SELECT `+feedColumns+` FROM feeds
+		WHERE enabled AND (next_fetch_at IS NULL OR next_fetch_at <= ?)
+		ORDER BY next_fetch_at NULLS FIRST, id;`,
+		unixTime(now)))
+}
+
+func (s *Store) ScheduleFeed(ctx context.Context, id int, next time.Time) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE feeds SET next_fetch_at = ?, consecutive_failures = 0 WHERE id = ?;",
+		unixTime(next), id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule feed %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *Store) ShortenSchedule(ctx context.Context, next time.Time, period int) error {
+	_, err := s.DB.ExecContext(ctx, `
+		UPDATE feeds SET next_fetch_at = ?1
+		WHERE next_fetch_at > ?1 AND fetch_interval = 0 AND ttl <= ?2
+			AND consecutive_failures = 0 AND skip_hours = 0 AND skip_days = 0;`,
+		unixTime(next), period)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule feeds: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) MarkNotModified(ctx context.Context, id int) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE feeds SET bytes_saved = bytes_saved + last_size WHERE id = ?;", id)
+	if err != nil {
+		return fmt.Errorf("failed to update feed %d: %w", id, err)
+	}
+	return nil
+}
+
+// FeedFailed повторяет правила DB.FeedFailed.
+func (s *Store) FeedFailed(ctx context.Context, id int, fetchErr string, next time.Time, maxFailures int) (db.Feed, error) {
+	feed, err := scanFeed(s.DB.QueryRowContext(ctx, `
+		UPDATE feeds SET
+			consecutive_failures = consecutive_failures + 1,
+			last_error = ?2,
+			last_error_at = ?3,
+			next_fetch_at = ?4,
+			enabled = enabled AND (?5 <= 0 OR consecutive_failures + 1 < ?5)
+		WHERE id = ?1
+		RETURNING `+feedColumns+`;`,
+		id, fetchErr, unixTime(time.Now()), unixTime(next), maxFailures))
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.Feed{}, db.ErrNotFound
+	}
+	if err != nil {
+		return db.Feed{}, fmt.Errorf("failed to record error for feed %d: %w", id, err)
+	}
+	return feed, nil
+}
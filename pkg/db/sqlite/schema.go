@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrations - версии схемы по порядку. Номер применённой версии хранится
+// в PRAGMA user_version; новые изменения добавляются в конец списка.
+var migrations = []string{
+	// 1: таблицы и индексы. IF NOT EXISTS нужен файлам, созданным до
+	// появления версий схемы
+	`
+	CREATE TABLE IF NOT EXISTS feeds (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL UNIQUE,
+		title TEXT NOT NULL DEFAULT '',
+		custom_title TEXT NOT NULL DEFAULT '',
+		site_link TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		icon TEXT NOT NULL DEFAULT '',
+		added_at INTEGER NOT NULL,
+		enabled INTEGER NOT NULL DEFAULT 1,
+		fetch_interval INTEGER NOT NULL DEFAULT 0,
+		ttl INTEGER NOT NULL DEFAULT 0,
+		skip_hours INTEGER NOT NULL DEFAULT 0,
+		skip_days INTEGER NOT NULL DEFAULT 0,
+		next_fetch_at INTEGER,
+		etag TEXT NOT NULL DEFAULT '',
+		last_modified TEXT NOT NULL DEFAULT '',
+		last_size INTEGER NOT NULL DEFAULT 0,
+		bytes_saved INTEGER NOT NULL DEFAULT 0,
+		consecutive_failures INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		last_error_at INTEGER,
+		in_config INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE IF NOT EXISTS news (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		feed_id INTEGER REFERENCES feeds (id) ON DELETE CASCADE,
+		name TEXT,
+		description TEXT,
+		publication_date INTEGER,
+		link TEXT,
+		guid TEXT NOT NULL,
+		content_hash TEXT,
+		updated_at INTEGER
+	);
+	CREATE TABLE IF NOT EXISTS news_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		news_id INTEGER NOT NULL REFERENCES news (id) ON DELETE CASCADE,
+		name TEXT,
+		description TEXT,
+		content_hash TEXT,
+		replaced_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS news_publication_date_idx ON news (publication_date DESC, id DESC);
+	CREATE UNIQUE INDEX IF NOT EXISTS news_feed_guid_idx ON news (feed_id, guid);
+	CREATE INDEX IF NOT EXISTS news_revisions_news_id_idx ON news_revisions (news_id);
+	`,
+	// 2: полнотекстовый индекс FTS5 по заголовку и описанию. Таблица хранит
+	// только индекс, содержимое берётся из news; триггеры держат их в согласии
+	`
+	CREATE VIRTUAL TABLE news_fts USING fts5 (
+		name, description,
+		content = 'news', content_rowid = 'id',
+		tokenize = 'unicode61 remove_diacritics 2'
+	);
+	CREATE TRIGGER news_fts_insert AFTER INSERT ON news BEGIN
+		INSERT INTO news_fts (rowid, name, description) VALUES (new.id, new.name, new.description);
+	END;
+	CREATE TRIGGER news_fts_delete AFTER DELETE ON news BEGIN
+		INSERT INTO news_fts (news_fts, rowid, name, description) VALUES ('delete', old.id, old.name, old.description);
+	END;
+	CREATE TRIGGER news_fts_update AFTER UPDATE OF name, description ON news BEGIN
+		INSERT INTO news_fts (news_fts, rowid, name, description) VALUES ('delete', old.id, old.name, old.description);
+		INSERT INTO news_fts (rowid, name, description) VALUES (new.id, new.name, new.description);
+	END;
+	INSERT INTO news_fts (news_fts) VALUES ('rebuild');
+	`,
+	// 3: автор записи и индексы для фильтров списка
+	`
+	ALTER TABLE news ADD COLUMN author TEXT NOT NULL DEFAULT '';
+	CREATE INDEX news_feed_publication_date_idx ON news (feed_id, publication_date DESC, id DESC);
+	CREATE INDEX news_author_idx ON news (author COLLATE NOCASE);
+	`,
+	// 4: папка ленты
+	`
+	ALTER TABLE feeds ADD COLUMN category TEXT NOT NULL DEFAULT '';
+	`,
+}
+
+// Version возвращает применённую версию схемы и последнюю известную.
+func (s *Store) Version(ctx context.Context) (current, latest int, err error) {
+	if err := s.DB.QueryRowContext(ctx, "PRAGMA user_version;").Scan(&current); err != nil {
+		return 0, 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return current, len(migrations), nil
+}
+
+// migrate применяет недостающие версии, каждую в своей транзакции вместе
+// с новым user_version. Соединение у хранилища одно, так что другие
+// запросы того же процесса в это время не выполняются.
+func (s *Store) migrate(ctx context.Context) error {
+	current, latest, err := s.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than supported %d", current, latest)
+	}
+	for version := current + 1; version <= latest; version++ {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, migrations[version-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %w", version, err)
+		}
+		// PRAGMA не принимает параметры
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d;", version)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to set schema version %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"goNews/pkg/db"
+	"strings"
+	"unicode"
+)
+
+// Search ищет записи через FTS5. Запрос понимает тот же синтаксис, что
+// и в Postgres (см. webSearchQuery). Стемминга у SQLite нет, поэтому каждое
+// слово вне кавычек ищется как префикс: «generic» найдёт и «generics».
+// Ранг - bm25 с двойным весом заголовка.
+func (s *Store) Search(ctx context.Context, query string, limit int) ([]db.SearchResult, error) {
+	match := webSearchQuery(query)
+	result := make([]db.SearchResult, 0)
+	if match == "" {
+		return result, nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT n.id, COALESCE(n.feed_id, 0), COALESCE(NULLIF(f.custom_title, ''), f.title, ''), n.name, n.description,
+			n.publication_date, n.link, n.author, n.guid, n.updated_at,
+			(SELECT count(*) FROM news_revisions r WHERE r.news_id = n.id),
+			-bm25(news_fts, 2.0, 1.0),
+			CASE WHEN COALESCE(n.description, '') = ''
+				THEN snippet(news_fts, 0, ?3, ?4, '…', 30)
+				ELSE snippet(news_fts, 1, ?3, ?4, '…', 30) END
+		FROM news_fts
+		JOIN news n ON n.id = news_fts.rowid
+		LEFT JOIN feeds f ON f.id = n.feed_id
+		WHERE news_fts MATCH ?1
+		ORDER BY bm25(news_fts, 2.0, 1.0), n.publication_date DESC NULLS LAST, n.id DESC LIMIT ?2;`,
+		match, limit, db.SnippetStart, db.SnippetStop)
+	if err != nil {
+		return nil, fmt.Errorf("search error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var found db.SearchResult
+		var name, description, link sql.NullString
+		var published, updated sql.NullInt64
+		if err := rows.Scan(&found.ID, &found.FeedID, &found.FeedTitle, &name, &description, &published,
+			&link, &found.Author, &found.GUID, &updated, &found.Revisions, &found.Rank, &found.Snippet); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		found.Name, found.Description, found.Link = name.String, description.String, link.String
+		found.Snippet = db.Highlight(found.Snippet)
+		if published.Valid {
+			found.PublicationDate = timeOf(published.Int64)
+		}
+		found.UpdatedAt = timePtr(updated)
+		result = append(result, found)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return result, nil
+}
+
+// ftsQuery строит запрос FTS5, которому подходят записи со всеми словами
+// terms в начале слов текста. Слова берутся в кавычки, чтобы не разбирались
+// как операторы FTS5.
+func ftsQuery(terms []string) string {
+	match := make([]string, 0, len(terms))
+	for _, term := range terms {
+		match = append(match, `"`+term+`"*`)
+	}
+	return strings.Join(match, " ")
+}
+
+// webSearchQuery переводит синтаксис websearch_to_tsquery в запрос FTS5.
+// Слова нужны все и ищутся как префиксы, текст в кавычках - фраза FTS5,
+// or между словами - OR, а слово или фраза с минусом исключаются через NOT.
+// Часть запроса между or из одних исключений FTS5 выразить не может, она
+// отбрасывается. Пустая строка - в запросе нечего искать.
+func webSearchQuery(query string) string {
+	type clause struct{ include, exclude []string }
+	var clauses []clause
+	var cur clause
+	or := false
+	add := func(text string, phrase, negate bool) {
+		terms := db.SearchTerms(text)
+		if len(terms) == 0 {
+			return
+		}
+		// Слова берутся в кавычки, чтобы не разбирались как операторы FTS5
+		match := `"` + strings.Join(terms, " ") + `"`
+		if !phrase && len(terms) == 1 {
+			match += "*"
+		}
+		if or && len(cur.include)+len(cur.exclude) > 0 {
+			clauses = append(clauses, cur)
+			cur = clause{}
+		}
+		or = false
+		if negate {
+			cur.exclude = append(cur.exclude, match)
+		} else {
+			cur.include = append(cur.include, match)
+		}
+	}
+
+	for rest := strings.TrimSpace(query); rest != ""; rest = strings.TrimSpace(rest) {
+		negate := rest[0] == '-'
+		if negate {
+			rest = rest[1:]
+		}
+		if strings.HasPrefix(rest, `"`) {
+			phrase := rest[1:]
+			rest = ""
+			if end := strings.IndexByte(phrase, '"'); end >= 0 {
+				phrase, rest = phrase[:end], phrase[end+1:]
+			}
+			add(phrase, true, negate)
+			continue
+		}
+		end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+		if !negate && strings.EqualFold(word, "or") {
+			or = true
+			continue
+		}
+		add(word, false, negate)
+	}
+	clauses = append(clauses, cur)
+
+	var parts []string
+	for _, c := range clauses {
+		if len(c.include) == 0 {
+			continue
+		}
+		part := "(" + strings.Join(c.include, " AND ") + ")"
+		for _, ex := range c.exclude {
+			part += " NOT " + ex
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " OR ")
+}
@@ -0,0 +1,234 @@
+// Package sqlite - хранилище новостей и лент в файле SQLite. Схема и правила
+// те же, что у Postgres-хранилища из пакета db: записи определяются парой
+// (лента, guid), правки сохраняются в news_revisions. Драйвер написан на Go,
+// поэтому сервер собирается в один бинарник без внешней базы.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"goNews/pkg/db"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Store реализует db.Store поверх SQLite.
+type Store struct {
+	DB *sql.DB
+}
+
+var _ db.Store = (*Store)(nil)
+
+// New открывает или создаёт файл базы path и доводит его схему до текущей.
+func New(ctx context.Context, path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	// Внешние ключи в SQLite включаются на каждом соединении
+	dsn := "file:" + path + "?" + url.Values{"_pragma": {
+		"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)",
+	}}.Encode()
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	// SQLite допускает одного писателя: одно соединение избавляет от SQLITE_BUSY
+	conn.SetMaxOpenConns(1)
+
+	store := &Store{DB: conn}
+	if err := store.migrate(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Время хранится в микросекундах Unix, как и точность TIMESTAMPTZ в Postgres:
+// такие значения сравниваются и сортируются без учёта формата и часового пояса.
+func unixTime(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+func timeOf(v int64) time.Time {
+	return time.UnixMicro(v).UTC()
+}
+
+func timePtr(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := timeOf(v.Int64)
+	return &t
+}
+
+func (s *Store) News(ctx context.Context, q db.NewsQuery) (db.NewsPage, error) {
+	args := []interface{}{q.Limit + 1}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("?%d", len(args))
+	}
+	conds, err := newsFilter(q, arg)
+	if err != nil {
+		return db.NewsPage{}, err
+	}
+	order := "DESC"
+	switch {
+	case q.After != nil:
+		conds = append(conds, fmt.Sprintf("(n.publication_date, n.id) < (%s, %s)", arg(unixTime(q.After.PublishedAt)), arg(q.After.ID)))
+	case q.Before != nil:
+		conds = append(conds, fmt.Sprintf("(n.publication_date, n.id) > (%s, %s)", arg(unixTime(q.Before.PublishedAt)), arg(q.Before.ID)))
+		order = "ASC"
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT n.id, COALESCE(n.feed_id, 0), COALESCE(NULLIF(f.custom_title, ''), f.title, ''), n.name, n.description,
+			n.publication_date, n.link, n.author, n.guid, n.updated_at,
+			(SELECT count(*) FROM news_revisions r WHERE r.news_id = n.id)
+		FROM news n
+		LEFT JOIN feeds f ON f.id = n.feed_id
+		`+where+`
+		ORDER BY n.publication_date `+order+`, n.id `+order+` LIMIT ?1;`,
+		args...)
+	if err != nil {
+		return db.NewsPage{}, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]db.News, 0)
+	for rows.Next() {
+		var news db.News
+		var name, description, link sql.NullString
+		var published, updated sql.NullInt64
+		if err := rows.Scan(&news.ID, &news.FeedID, &news.FeedTitle, &name, &description, &published,
+			&link, &news.Author, &news.GUID, &updated, &news.Revisions); err != nil {
+			return db.NewsPage{}, fmt.Errorf("scan error: %w", err)
+		}
+		news.Name, news.Description, news.Link = name.String, description.String, link.String
+		if published.Valid {
+			news.PublicationDate = timeOf(published.Int64)
+		}
+		news.UpdatedAt = timePtr(updated)
+		result = append(result, news)
+	}
+	if err := rows.Err(); err != nil {
+		return db.NewsPage{}, fmt.Errorf("rows error: %w", err)
+	}
+	return db.PageOf(q, result), nil
+}
+
+// newsFilter повторяет фильтры DB.News. Слова ищутся в news_fts так же,
+// как в Search, а автор сравнивается без учёта регистра только для латиницы:
+// NOCASE в SQLite не знает других алфавитов.
+func newsFilter(q db.NewsQuery, arg func(v interface{}) string) ([]string, error) {
+	var conds []string
+	if len(q.FeedIDs) > 0 {
+		ids, err := json.Marshal(q.FeedIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode feed ids: %w", err)
+		}
+		conds = append(conds, "n.feed_id IN (SELECT value FROM json_each("+arg(string(ids))+"))")
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "n.publication_date >= "+arg(unixTime(q.Since)))
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "n.publication_date < "+arg(unixTime(q.Until)))
+	}
+	for _, keyword := range q.Include {
+		match := ftsQuery(db.SearchTerms(keyword))
+		if match == "" {
+			conds = append(conds, "0")
+			continue
+		}
+		conds = append(conds, "n.id IN (SELECT rowid FROM news_fts WHERE news_fts MATCH "+arg(match)+")")
+	}
+	for _, keyword := range q.Exclude {
+		if match := ftsQuery(db.SearchTerms(keyword)); match != "" {
+			conds = append(conds, "n.id NOT IN (SELECT rowid FROM news_fts WHERE news_fts MATCH "+arg(match)+")")
+		}
+	}
+	if q.Author != "" {
+		conds = append(conds, "n.author = "+arg(q.Author)+" COLLATE NOCASE")
+	}
+	return conds, nil
+}
+
+// StoreNews повторяет правила DB.StoreNews: из повторов в пачке берётся
+// первая запись, а при смене хеша содержимого прежняя версия уходит
+// в news_revisions.
+func (s *Store) StoreNews(ctx context.Context, news []db.News) error {
+	if len(news) == 0 {
+		return nil
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	revision, err := tx.PrepareContext(ctx, `
+		INSERT INTO news_revisions (news_id, name, description, content_hash, replaced_at)
+		SELECT id, name, description, content_hash, ?4 FROM news
+		WHERE feed_id = ?1 AND guid = ?2 AND content_hash IS NOT NULL AND content_hash <> ?3;`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare revisions: %w", err)
+	}
+	defer revision.Close()
+	upsert, err := tx.PrepareContext(ctx, `
+		INSERT INTO news (feed_id, name, description, publication_date, link, guid, content_hash, author)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?9)
+		ON CONFLICT (feed_id, guid) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			link = excluded.link,
+			author = excluded.author,
+			content_hash = excluded.content_hash,
+			updated_at = CASE WHEN news.content_hash IS NULL THEN news.updated_at ELSE ?8 END
+		WHERE news.content_hash IS NOT excluded.content_hash;`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer upsert.Close()
+
+	type key struct {
+		feedID int
+		guid   string
+	}
+	seen := make(map[key]bool, len(news))
+	now := unixTime(time.Now())
+	for _, n := range news {
+		k := key{n.FeedID, n.GUID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		hash := n.ContentHash
+		if hash == "" {
+			hash = db.ContentHash(n.Name, n.Description)
+		}
+		if _, err := revision.ExecContext(ctx, n.FeedID, n.GUID, hash, now); err != nil {
+			return fmt.Errorf("failed to save revisions: %w", err)
+		}
+		if _, err := upsert.ExecContext(ctx, n.FeedID, n.Name, n.Description, unixTime(n.PublicationDate),
+			n.Link, n.GUID, hash, now, n.Author); err != nil {
+			return fmt.Errorf("batch insert error: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) Close() {
+	s.DB.Close()
+}
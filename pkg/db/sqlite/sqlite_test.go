@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goNews/pkg/db"
+	"goNews/pkg/db/storetest"
+)
+
+// TestStore прогоняет общие проверки хранилищ на файле во временном каталоге
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) db.Store {
+		store, err := New(context.Background(), filepath.Join(t.TempDir(), "data", "news.db"))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return store
+	}, storetest.Options{
+		NoStemming: "SQLite FTS5 has no Snowball stemmer, words are matched as prefixes",
+	})
+}
+
+// TestReopen проверяет, что записи и даты переживают перезапуск
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "news.db")
+	store, err := New(ctx, path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	feed, err := store.AddFeed(ctx, "http://example.com/rss")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	published := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+	if err := store.StoreNews(ctx, []db.News{{FeedID: feed.ID, GUID: "1", Name: "News", PublicationDate: published}}); err != nil {
+		t.Fatalf("StoreNews() error = %v", err)
+	}
+	store.Close()
+
+	store, err = New(ctx, path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer store.Close()
+	if current, latest, err := store.Version(ctx); err != nil || current != latest {
+		t.Errorf("Version() = %d, %d, %v, expected the latest schema", current, latest, err)
+	}
+	page, err := store.News(ctx, db.NewsQuery{Limit: 10})
+	news := page.News
+	if err != nil {
+		t.Fatalf("News() error = %v", err)
+	}
+	if len(news) != 1 || !news[0].PublicationDate.Equal(published) || news[0].PublicationDate.Location() != time.UTC {
+		t.Errorf("Unexpected news after reopen: %+v", news)
+	}
+	if added, _, err := store.SyncConfigFeeds(ctx, []string{"http://example.com/rss"}); err != nil || len(added) != 1 {
+		t.Errorf("SyncConfigFeeds() = %v, %v", added, err)
+	}
+}
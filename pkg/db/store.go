@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Store - хранилище новостей и лент, с которым работают API и поллер.
+// DB реализует его поверх Postgres; реализации должны сохранять поведение
+// DB, включая ошибки ErrNotFound, ErrFeedExists и ErrNoFeedPatch.
+type Store interface {
+	// News возвращает страницу записей, новые первыми.
+	News(ctx context.Context, q NewsQuery) (NewsPage, error)
+	// StoreNews сохраняет пачку записей, учитывая правки уже известных.
+	StoreNews(ctx context.Context, news []News) error
+	// Search ищет записи по словам из query, самые релевантные первыми.
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+
+	AddFeed(ctx context.Context, url string) (Feed, error)
+	SyncConfigFeeds(ctx context.Context, urls []string) (added, removed []string, err error)
+	CreateFeed(ctx context.Context, feed Feed) (Feed, error)
+	Feed(ctx context.Context, id int) (Feed, error)
+	Feeds(ctx context.Context) ([]Feed, error)
+	UpdateFeed(ctx context.Context, id int, patch FeedPatch) (Feed, error)
+	DeleteFeed(ctx context.Context, id int) error
+
+	// Методы поллера: метаданные канала, расписание и ошибки загрузки.
+	UpdateFeedMeta(ctx context.Context, feed Feed) error
+	DueFeeds(ctx context.Context, now time.Time) ([]Feed, error)
+	ScheduleFeed(ctx context.Context, id int, next time.Time) error
+	ShortenSchedule(ctx context.Context, next time.Time, period int) error
+	MarkNotModified(ctx context.Context, id int) error
+	FeedFailed(ctx context.Context, id int, fetchErr string, next time.Time, maxFailures int) (Feed, error)
+
+	Close()
+}
+
+var _ Store = (*DB)(nil)
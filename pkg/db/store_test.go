@@ -0,0 +1,30 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"goNews/pkg/config"
+	"goNews/pkg/db"
+	"goNews/pkg/db/storetest"
+)
+
+// TestStore прогоняет общие проверки хранилищ на Postgres
+func TestStore(t *testing.T) {
+	conf, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	storetest.Run(t, func(t *testing.T) db.Store {
+		ctx := context.Background()
+		errChan := make(chan error, 1)
+		store := db.New(ctx, conf.Database, errChan)
+		if store == nil {
+			t.Fatalf("Failed to initialize database: %v", <-errChan)
+		}
+		if _, err := store.Pool.Exec(ctx, "TRUNCATE TABLE news, feeds RESTART IDENTITY CASCADE;"); err != nil {
+			t.Fatalf("Failed to truncate tables: %v", err)
+		}
+		return store
+	}, storetest.Options{})
+}
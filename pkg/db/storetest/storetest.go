@@ -0,0 +1,543 @@
+// Package storetest - общие проверки реализаций db.Store. Каждое хранилище
+// запускает их в своих тестах, чтобы все они вели себя одинаково.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"goNews/pkg/db"
+)
+
+// Options - известные отличия хранилища от Postgres. Непустая причина
+// пропускает соответствующие проверки и выводится в журнал теста.
+type Options struct {
+	// NoStemming - почему хранилище не приводит слова к основе.
+	NoStemming string
+	// NoWebSearch - почему хранилище не понимает операторы
+	// websearch_to_tsquery.
+	NoWebSearch string
+}
+
+// Run прогоняет проверки на пустых хранилищах, которые создаёт open.
+func Run(t *testing.T, open func(t *testing.T) db.Store, opts Options) {
+	tests := []struct {
+		name string
+		skip string
+		test func(t *testing.T, store db.Store)
+	}{
+		{name: "StoreNews", test: testStoreNews},
+		{name: "Pages", test: testPages},
+		{name: "Filters", test: testFilters},
+		{name: "Feeds", test: testFeeds},
+		{name: "SyncConfigFeeds", test: testSyncConfigFeeds},
+		{name: "Search", test: testSearch},
+		{name: "SnippetMarkup", test: testSnippetMarkup},
+		{name: "WebSearch", skip: opts.NoWebSearch, test: testWebSearch},
+		{name: "Stemming", skip: opts.NoStemming, test: testStemming},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.skip != "" {
+				t.Skip(tt.skip)
+			}
+			store := open(t)
+			defer store.Close()
+			tt.test(t, store)
+		})
+	}
+}
+
+// testStoreNews проверяет порядок выдачи и учёт правок записей
+func testStoreNews(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	feed, err := store.AddFeed(ctx, "http://example.com/rss")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	batches := [][]db.News{
+		{
+			{FeedID: feed.ID, GUID: "a", Name: "A", Description: "first", PublicationDate: day(1)},
+			{FeedID: feed.ID, GUID: "b", Name: "B", Description: "second", PublicationDate: day(2)},
+			{FeedID: feed.ID, GUID: "b", Name: "B dup", Description: "ignored", PublicationDate: day(2)},
+		},
+		// Та же запись без изменений не создаёт ревизию
+		{{FeedID: feed.ID, GUID: "a", Name: "A", Description: "first", PublicationDate: day(1)}},
+		{{FeedID: feed.ID, GUID: "a", Name: "A", Description: "edited", PublicationDate: day(1)}},
+	}
+	for _, batch := range batches {
+		if err := store.StoreNews(ctx, batch); err != nil {
+			t.Fatalf("StoreNews() error = %v", err)
+		}
+	}
+
+	page, err := store.News(ctx, db.NewsQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("News() error = %v", err)
+	}
+	news := page.News
+	if len(news) != 2 || news[0].GUID != "b" || news[1].GUID != "a" {
+		t.Fatalf("Unexpected news order: %+v", news)
+	}
+	if news[0].Name != "B" || news[0].Revisions != 0 || news[0].UpdatedAt != nil {
+		t.Errorf("Unexpected first news: %+v", news[0])
+	}
+	if news[1].Description != "edited" || news[1].Revisions != 1 || news[1].UpdatedAt == nil {
+		t.Errorf("Expected edited news with one revision, got %+v", news[1])
+	}
+
+	if page, _ := store.News(ctx, db.NewsQuery{Limit: 1}); len(page.News) != 1 || page.Next == nil {
+		t.Errorf("Expected 1 news and next page, got %+v", page)
+	}
+	if err := store.DeleteFeed(ctx, feed.ID); err != nil {
+		t.Fatalf("DeleteFeed() error = %v", err)
+	}
+	if page, _ := store.News(ctx, db.NewsQuery{Limit: 10}); len(page.News) != 0 {
+		t.Errorf("News of deleted feed remain: %+v", page.News)
+	}
+
+	// Запись удалённой ленты отклоняет пачку целиком
+	other, err := store.AddFeed(ctx, "http://example.com/other")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	err = store.StoreNews(ctx, []db.News{
+		{FeedID: other.ID, GUID: "c", Name: "C", PublicationDate: day(3)},
+		{FeedID: feed.ID, GUID: "d", Name: "D", PublicationDate: day(4)},
+	})
+	if err == nil {
+		t.Error("Expected error storing news of a deleted feed")
+	}
+	if page, _ := store.News(ctx, db.NewsQuery{Limit: 10}); len(page.News) != 0 {
+		t.Errorf("Failed batch was partially stored: %+v", page.News)
+	}
+}
+
+// testPages проверяет листание страниц вперёд и назад, в том числе
+// по записям с одинаковой датой и после появления новых записей
+func testPages(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	feed, err := store.AddFeed(ctx, "http://example.com/rss")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	// Записи 3 и 4 опубликованы одновременно и различаются только id
+	dates := []int{1, 2, 3, 3, 5, 6, 7}
+	batch := make([]db.News, 0, len(dates))
+	for i, d := range dates {
+		batch = append(batch, db.News{FeedID: feed.ID, GUID: fmt.Sprint(i + 1), Name: fmt.Sprint("News ", i+1),
+			PublicationDate: time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)})
+	}
+	if err := store.StoreNews(ctx, batch); err != nil {
+		t.Fatalf("StoreNews() error = %v", err)
+	}
+
+	load := func(q db.NewsQuery) db.NewsPage {
+		t.Helper()
+		page, err := store.News(ctx, q)
+		if err != nil {
+			t.Fatalf("News(%+v) error = %v", q, err)
+		}
+		return page
+	}
+	guids := func(page db.NewsPage) []string {
+		result := make([]string, 0, len(page.News))
+		for _, n := range page.News {
+			result = append(result, n.GUID)
+		}
+		return result
+	}
+
+	first := load(db.NewsQuery{Limit: 3})
+	if got := guids(first); !reflect.DeepEqual(got, []string{"7", "6", "5"}) || first.Prev != nil || first.Next == nil {
+		t.Fatalf("Unexpected first page %v, prev %v, next %v", got, first.Prev, first.Next)
+	}
+	// Новая запись не сдвигает уже открытые страницы
+	late := db.News{FeedID: feed.ID, GUID: "8", Name: "News 8", PublicationDate: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)}
+	if err := store.StoreNews(ctx, []db.News{late}); err != nil {
+		t.Fatalf("StoreNews() error = %v", err)
+	}
+	second := load(db.NewsQuery{Limit: 3, After: first.Next})
+	if got := guids(second); !reflect.DeepEqual(got, []string{"4", "3", "2"}) || second.Prev == nil || second.Next == nil {
+		t.Fatalf("Unexpected second page %v, prev %v, next %v", got, second.Prev, second.Next)
+	}
+	last := load(db.NewsQuery{Limit: 3, After: second.Next})
+	if got := guids(last); !reflect.DeepEqual(got, []string{"1"}) || last.Next != nil {
+		t.Fatalf("Unexpected last page %v, next %v", got, last.Next)
+	}
+
+	back := load(db.NewsQuery{Limit: 3, Before: last.Prev})
+	if got := guids(back); !reflect.DeepEqual(got, []string{"4", "3", "2"}) || back.Next == nil || back.Prev == nil {
+		t.Fatalf("Unexpected page back %v, prev %v, next %v", got, back.Prev, back.Next)
+	}
+	top := load(db.NewsQuery{Limit: 3, Before: back.Prev})
+	if got := guids(top); !reflect.DeepEqual(got, []string{"7", "6", "5"}) || top.Prev == nil {
+		t.Fatalf("Unexpected page back %v, prev %v", got, top.Prev)
+	}
+	if got := guids(load(db.NewsQuery{Limit: 3, Before: top.Prev})); !reflect.DeepEqual(got, []string{"8"}) {
+		t.Errorf("Expected the late news before the first page, got %v", got)
+	}
+}
+
+// testFilters проверяет фильтры списка по лентам, датам, словам и автору
+func testFilters(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	weekly, err := store.AddFeed(ctx, "http://example.com/weekly")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	other, err := store.AddFeed(ctx, "http://example.com/other")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	err = store.StoreNews(ctx, []db.News{
+		{FeedID: weekly.ID, GUID: "1", Name: "Generics in Go", Description: "Type parameters land", Author: "Jane Roe", PublicationDate: day(1)},
+		{FeedID: weekly.ID, GUID: "2", Name: "pgx v5 released", Description: "New Postgres driver", Author: "Jack", PublicationDate: day(3)},
+		{FeedID: weekly.ID, GUID: "3", Name: "pgx pool tuning", Description: "Connection pools", Author: "jane roe", PublicationDate: day(5)},
+		{FeedID: other.ID, GUID: "4", Name: "pgx in production", Description: "Postgres driver story", Author: "Bob", PublicationDate: day(4)},
+		{FeedID: other.ID, GUID: "5", Name: "Rust news", Description: "Nothing about Go", PublicationDate: day(6)},
+	})
+	if err != nil {
+		t.Fatalf("StoreNews() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    db.NewsQuery
+		expected []string
+	}{
+		{name: "one feed", query: db.NewsQuery{FeedIDs: []int{weekly.ID}}, expected: []string{"3", "2", "1"}},
+		{name: "both feeds", query: db.NewsQuery{FeedIDs: []int{weekly.ID, other.ID}}, expected: []string{"5", "3", "4", "2", "1"}},
+		{name: "date range", query: db.NewsQuery{Since: day(3), Until: day(5)}, expected: []string{"4", "2"}},
+		{name: "keyword", query: db.NewsQuery{Include: []string{"pgx"}}, expected: []string{"3", "4", "2"}},
+		{name: "phrase words", query: db.NewsQuery{Include: []string{"postgres driver"}}, expected: []string{"4", "2"}},
+		{name: "excluded keyword", query: db.NewsQuery{Include: []string{"pgx"}, Exclude: []string{"driver"}}, expected: []string{"3"}},
+		{name: "author ignores case", query: db.NewsQuery{Author: "JANE ROE"}, expected: []string{"3", "1"}},
+		{name: "combined", query: db.NewsQuery{FeedIDs: []int{weekly.ID}, Since: day(2), Include: []string{"pgx"}}, expected: []string{"3", "2"}},
+		{name: "keyword without words", query: db.NewsQuery{Include: []string{"!!!"}}, expected: []string{}},
+	}
+	for _, tt := range tests {
+		tt.query.Limit = 10
+		page, err := store.News(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("%s: News() error = %v", tt.name, err)
+		}
+		got := make([]string, 0, len(page.News))
+		for _, n := range page.News {
+			got = append(got, n.GUID)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+
+	// Курсоры работают вместе с фильтрами
+	q := db.NewsQuery{Limit: 2, FeedIDs: []int{weekly.ID}}
+	first, err := store.News(ctx, q)
+	if err != nil || len(first.News) != 2 || first.Next == nil || first.News[1].Author != "Jack" {
+		t.Fatalf("Unexpected first filtered page %+v, error %v", first, err)
+	}
+	q.After = first.Next
+	if next, err := store.News(ctx, q); err != nil || len(next.News) != 1 || next.News[0].GUID != "1" || next.Next != nil {
+		t.Errorf("Unexpected second filtered page %+v, error %v", next, err)
+	}
+}
+
+// testFeeds проверяет управление лентами и их расписание
+func testFeeds(t *testing.T, store db.Store) {
+	ctx := context.Background()
+
+	created, err := store.CreateFeed(ctx, db.Feed{URL: "http://example.com/rss", Title: "Channel", Enabled: true, Category: "Tech"})
+	if err != nil {
+		t.Fatalf("CreateFeed() error = %v", err)
+	}
+	if created.Category != "Tech" {
+		t.Errorf("Expected category Tech, got %q", created.Category)
+	}
+	if _, err := store.CreateFeed(ctx, db.Feed{URL: "http://example.com/rss"}); !errors.Is(err, db.ErrFeedExists) {
+		t.Errorf("Expected ErrFeedExists, got %v", err)
+	}
+	if _, err := store.UpdateFeed(ctx, created.ID, db.FeedPatch{}); !errors.Is(err, db.ErrNoFeedPatch) {
+		t.Errorf("Expected ErrNoFeedPatch, got %v", err)
+	}
+	if _, err := store.Feed(ctx, 100); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	title := "Custom"
+	feed, err := store.UpdateFeed(ctx, created.ID, db.FeedPatch{Title: &title})
+	if err != nil || feed.Title != "Custom" {
+		t.Errorf("UpdateFeed() = %+v, %v", feed, err)
+	}
+	// Метаданные канала не перекрывают название пользователя
+	feed.Title = "Channel v2"
+	if err := store.UpdateFeedMeta(ctx, feed); err != nil {
+		t.Fatalf("UpdateFeedMeta() error = %v", err)
+	}
+	if feed, _ = store.Feed(ctx, created.ID); feed.Title != "Custom" {
+		t.Errorf("Expected custom title, got %q", feed.Title)
+	}
+	category := "News/World"
+	if feed, err = store.UpdateFeed(ctx, created.ID, db.FeedPatch{Category: &category}); err != nil ||
+		feed.Category != category || feed.Title != "Custom" {
+		t.Errorf("UpdateFeed() = %+v, %v", feed, err)
+	}
+
+	now := time.Now()
+	if due, _ := store.DueFeeds(ctx, now); len(due) != 1 {
+		t.Errorf("New feed must be due, got %+v", due)
+	}
+	for i := 0; i < 2; i++ {
+		if feed, err = store.FeedFailed(ctx, created.ID, "timeout", now.Add(time.Hour), 2); err != nil {
+			t.Fatalf("FeedFailed() error = %v", err)
+		}
+	}
+	if feed.Enabled || feed.ConsecutiveFailures != 2 || feed.LastError != "timeout" {
+		t.Errorf("Expected feed disabled after 2 failures, got %+v", feed)
+	}
+	if due, _ := store.DueFeeds(ctx, now.Add(2*time.Hour)); len(due) != 0 {
+		t.Errorf("Disabled feed must not be due, got %+v", due)
+	}
+
+	enabled := true
+	if feed, _ = store.UpdateFeed(ctx, created.ID, db.FeedPatch{Enabled: &enabled}); !feed.Enabled ||
+		feed.ConsecutiveFailures != 0 || feed.NextFetchAt != nil {
+		t.Errorf("Unexpected feed after re-enabling: %+v", feed)
+	}
+}
+
+// testSyncConfigFeeds проверяет сверку лент из настроек
+func testSyncConfigFeeds(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	manual, err := store.AddFeed(ctx, "http://example.com/manual")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+
+	steps := []struct {
+		urls        []string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{urls: []string{"http://example.com/a", "http://example.com/b", "http://example.com/a"},
+			wantAdded: []string{"http://example.com/a", "http://example.com/b"}},
+		{urls: []string{"http://example.com/a", "http://example.com/b"}},
+		{urls: []string{"http://example.com/a"}, wantRemoved: []string{"http://example.com/b"}},
+		{urls: []string{"http://example.com/a", "http://example.com/b"}, wantAdded: []string{"http://example.com/b"}},
+	}
+	for i, step := range steps {
+		added, removed, err := store.SyncConfigFeeds(ctx, step.urls)
+		if err != nil {
+			t.Fatalf("step %d: SyncConfigFeeds() error = %v", i, err)
+		}
+		// Порядок адресов хранилища не гарантируют
+		sort.Strings(added)
+		sort.Strings(removed)
+		if !reflect.DeepEqual(added, step.wantAdded) || !reflect.DeepEqual(removed, step.wantRemoved) {
+			t.Errorf("step %d: SyncConfigFeeds() = %v, %v, want %v, %v", i, added, removed, step.wantAdded, step.wantRemoved)
+		}
+	}
+
+	feeds, err := store.Feeds(ctx)
+	if err != nil {
+		t.Fatalf("Feeds() error = %v", err)
+	}
+	if len(feeds) != 3 {
+		t.Fatalf("Expected 3 feeds, got %+v", feeds)
+	}
+	for _, feed := range feeds {
+		if feed.ID == manual.ID && (feed.InConfig || !feed.Enabled) {
+			t.Errorf("Manual feed changed: %+v", feed)
+		}
+		if feed.ID != manual.ID && (!feed.InConfig || !feed.Enabled) {
+			t.Errorf("Config feed must be enabled: %+v", feed)
+		}
+	}
+}
+
+// testSearch проверяет поиск по словам на русском и английском, порядок
+// по релевантности и обновление индекса при правках и удалении
+func testSearch(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	feed, err := store.CreateFeed(ctx, db.Feed{URL: "http://example.com/rss", Title: "Go Blog", Enabled: true})
+	if err != nil {
+		t.Fatalf("CreateFeed() error = %v", err)
+	}
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	weather := db.News{FeedID: feed.ID, GUID: "weather", Name: "Погода в Москве",
+		Description: "Синоптики обещают снег и мороз", PublicationDate: day(2)}
+	err = store.StoreNews(ctx, []db.News{
+		{FeedID: feed.ID, GUID: "generics", Name: "Generics in Go",
+			Description: "Type parameters finally land in the compiler", PublicationDate: day(1)},
+		weather,
+		{FeedID: feed.ID, GUID: "pgx", Name: "pgx release",
+			Description: "New Postgres driver with generics support", PublicationDate: day(3)},
+	})
+	if err != nil {
+		t.Fatalf("StoreNews() error = %v", err)
+	}
+
+	search := func(query string) []db.SearchResult {
+		t.Helper()
+		found, err := store.Search(ctx, query, 10)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", query, err)
+		}
+		return found
+	}
+	guids := func(found []db.SearchResult) []string {
+		result := make([]string, 0, len(found))
+		for _, f := range found {
+			result = append(result, f.GUID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		// Совпадение в заголовке важнее, чем в описании
+		{query: "generics", expected: []string{"generics", "pgx"}},
+		{query: "Postgres driver", expected: []string{"pgx"}},
+		{query: "погода", expected: []string{"weather"}},
+		{query: "снег generics", expected: []string{}},
+		{query: "", expected: []string{}},
+	}
+	for _, tt := range tests {
+		if got := guids(search(tt.query)); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.expected)
+		}
+	}
+
+	found := search("снег")
+	if len(found) != 1 || found[0].FeedTitle != "Go Blog" || found[0].Rank <= 0 ||
+		!strings.Contains(found[0].Snippet, db.HighlightStart+"снег"+db.HighlightStop) {
+		t.Errorf("Unexpected search result: %+v", found)
+	}
+	if found, _ := store.Search(ctx, "generics", 1); len(found) != 1 {
+		t.Errorf("Expected 1 result with limit, got %d", len(found))
+	}
+
+	weather.Description = "Синоптики обещают дождь"
+	if err := store.StoreNews(ctx, []db.News{weather}); err != nil {
+		t.Fatalf("StoreNews() error = %v", err)
+	}
+	if got := guids(search("снег")); len(got) != 0 {
+		t.Errorf("Edited text is still found: %v", got)
+	}
+	if got := guids(search("дождь")); !reflect.DeepEqual(got, []string{"weather"}) {
+		t.Errorf("Search(дождь) = %v after edit", got)
+	}
+
+	if err := store.DeleteFeed(ctx, feed.ID); err != nil {
+		t.Fatalf("DeleteFeed() error = %v", err)
+	}
+	if got := guids(search("generics")); len(got) != 0 {
+		t.Errorf("News of deleted feed are found: %v", got)
+	}
+}
+
+// webSearchNews - записи для проверок синтаксиса запроса и основ слов.
+func webSearchNews(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	feed, err := store.AddFeed(ctx, "http://example.com/rss")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	err = store.StoreNews(ctx, []db.News{
+		{FeedID: feed.ID, GUID: "weather", Name: "Погода в Москве", Description: "Синоптики обещают снег"},
+		{FeedID: feed.ID, GUID: "go", Name: "Go release", Description: "The compiler runs faster"},
+		{FeedID: feed.ID, GUID: "rust", Name: "Rust release", Description: "Faster than Go compiler"},
+	})
+	if err != nil {
+		t.Fatalf("StoreNews() error = %v", err)
+	}
+}
+
+// searchCases проверяет, что каждому запросу подходят ровно записи
+// expected, в любом порядке
+func searchCases(t *testing.T, store db.Store, cases map[string][]string) {
+	t.Helper()
+	for query, expected := range cases {
+		found, err := store.Search(context.Background(), query, 10)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", query, err)
+		}
+		got := make([]string, 0, len(found))
+		for _, f := range found {
+			got = append(got, f.GUID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Search(%q) = %v, want %v", query, got, expected)
+		}
+	}
+}
+
+// testWebSearch проверяет синтаксис запроса websearch_to_tsquery: фразы
+// в кавычках, or и исключение минусом
+func testWebSearch(t *testing.T, store db.Store) {
+	webSearchNews(t, store)
+	searchCases(t, store, map[string][]string{
+		`"go release"`:           {"go"},
+		`"release go"`:           {},
+		"release -rust":          {"go"},
+		`compiler -"go release"`: {"rust"},
+		"снег or rust":           {"rust", "weather"},
+		"снег or rust -release":  {"weather"},
+		"release or":             {"go", "rust"},
+	})
+}
+
+// testStemming проверяет, что формы слова сводятся к одной основе
+func testStemming(t *testing.T, store db.Store) {
+	webSearchNews(t, store)
+	searchCases(t, store, map[string][]string{
+		"погоды":  {"weather"},
+		"running": {"go"},
+	})
+}
+
+// testSnippetMarkup проверяет, что разметка из текста записи попадает во
+// фрагмент экранированной, а тегами остаются только выделения
+func testSnippetMarkup(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	feed, err := store.AddFeed(ctx, "http://example.com/rss")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	err = store.StoreNews(ctx, []db.News{{FeedID: feed.ID, GUID: "xss", Name: "Метель",
+		Description: `<script>alert("x")</script> Метель & снег <img src=x onerror=alert(1)>`}})
+	if err != nil {
+		t.Fatalf("StoreNews() error = %v", err)
+	}
+
+	found, err := store.Search(ctx, "метель", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(found))
+	}
+	snippet := found[0].Snippet
+	if !strings.Contains(snippet, "&lt;script&gt;") || !strings.Contains(snippet, "&amp;") {
+		t.Errorf("Markup is not escaped in snippet %q", snippet)
+	}
+	if !strings.Contains(snippet, db.HighlightStart+"Метель"+db.HighlightStop) {
+		t.Errorf("Match is not highlighted in snippet %q", snippet)
+	}
+	rest := strings.ReplaceAll(strings.ReplaceAll(snippet, db.HighlightStart, ""), db.HighlightStop, "")
+	if strings.ContainsAny(rest, "<>") {
+		t.Errorf("Unexpected tags in snippet %q", snippet)
+	}
+}
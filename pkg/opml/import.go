@@ -0,0 +1,90 @@
+package opml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goNews/pkg/db"
+	"net/url"
+)
+
+// Result - итог импорта: адреса добавленных лент, уже известных и
+// отклонённых с причиной.
+type Result struct {
+	Added    []string `json:"added"`
+	Existing []string `json:"existing"`
+	Errors   []string `json:"errors"`
+}
+
+// Import добавляет ленты, которых ещё нет в хранилище. Повторный импорт
+// того же списка ничего не меняет: известные ленты сохраняют свои
+// настройки и только получают папку, если не были ни в одной. Новые
+// ленты включены и загружаются поллером на ближайшем цикле. Название из
+// списка становится пользовательским, как после переименования через API,
+// и не затирается заголовком канала при опросе.
+func Import(ctx context.Context, store db.Store, subs []Subscription) (Result, error) {
+	result := Result{Added: []string{}, Existing: []string{}, Errors: []string{}}
+	feeds, err := store.Feeds(ctx)
+	if err != nil {
+		return result, err
+	}
+	known := make(map[string]db.Feed, len(feeds))
+	for _, f := range feeds {
+		known[f.URL] = f
+	}
+
+	for _, sub := range subs {
+		if u, err := url.Parse(sub.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: not an absolute http(s) address", sub.URL))
+			continue
+		}
+		f, ok := known[sub.URL]
+		if !ok {
+			f, err = store.CreateFeed(ctx, db.Feed{
+				URL:      sub.URL,
+				SiteLink: sub.SiteLink,
+				Enabled:  true,
+				Category: sub.Category,
+			})
+			switch {
+			case err == nil:
+				if sub.Title != "" {
+					if f, err = store.UpdateFeed(ctx, f.ID, db.FeedPatch{Title: &sub.Title}); err != nil {
+						return result, err
+					}
+				}
+				known[sub.URL] = f
+				result.Added = append(result.Added, sub.URL)
+			case errors.Is(err, db.ErrFeedExists):
+				// Ленту добавили через API, пока шёл импорт
+				result.Existing = append(result.Existing, sub.URL)
+			default:
+				return result, err
+			}
+			continue
+		}
+
+		if f.Category == "" && sub.Category != "" {
+			if f, err = store.UpdateFeed(ctx, f.ID, db.FeedPatch{Category: &sub.Category}); err != nil {
+				return result, err
+			}
+		}
+		known[sub.URL] = f
+		result.Existing = append(result.Existing, sub.URL)
+	}
+	return result, nil
+}
+
+// Export возвращает все ленты хранилища как подписки в порядке добавления.
+// Название - пользовательское, а если его нет, заголовок канала.
+func Export(ctx context.Context, store db.Store) ([]Subscription, error) {
+	feeds, err := store.Feeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]Subscription, 0, len(feeds))
+	for _, f := range feeds {
+		subs = append(subs, Subscription{URL: f.URL, Title: f.Title, SiteLink: f.SiteLink, Category: f.Category})
+	}
+	return subs, nil
+}
@@ -0,0 +1,144 @@
+// Package opml читает и пишет списки подписок в формате OPML 2.0, которым
+// обмениваются RSS-ридеры.
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"golang.org/x/text/encoding/htmlindex"
+	"io"
+	"strings"
+	"time"
+)
+
+// Subscription - лента из списка подписок.
+type Subscription struct {
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	SiteLink string `json:"site_link"`
+	// Category - папка ленты, вложенные папки разделены «/».
+	Category string `json:"category"`
+}
+
+// Document - документ OPML. Подписки - outline с атрибутом xmlUrl,
+// папки - outline без него, содержащие другие outline.
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Parse читает подписки из документа OPML 1.0 или 2.0 в порядке
+// документа. Outline без xmlUrl и без вложенных пропускаются.
+func Parse(r io.Reader) ([]Subscription, error) {
+	d := xml.NewDecoder(r)
+	// Списки из старых ридеров бывают не в UTF-8 и с HTML-сущностями
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported charset %q: %w", label, err)
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+
+	var doc Document
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OPML: %w", err)
+	}
+	subs := make([]Subscription, 0)
+	collect(&subs, doc.Body.Outlines, nil)
+	return subs, nil
+}
+
+func collect(subs *[]Subscription, outlines []Outline, folders []string) {
+	for _, o := range outlines {
+		name := strings.TrimSpace(o.Text)
+		if name == "" {
+			name = strings.TrimSpace(o.Title)
+		}
+		if u := strings.TrimSpace(o.XMLURL); u != "" {
+			*subs = append(*subs, Subscription{
+				URL:      u,
+				Title:    name,
+				SiteLink: strings.TrimSpace(o.HTMLURL),
+				Category: strings.Join(folders, "/"),
+			})
+			continue
+		}
+		// Безымянная папка не добавляет уровня
+		inner := folders
+		if name != "" {
+			inner = append(folders[:len(folders):len(folders)], strings.ReplaceAll(name, "/", "-"))
+		}
+		collect(subs, o.Outlines, inner)
+	}
+}
+
+// New собирает документ из подписок. Ленты с папкой вкладываются в
+// outline папок; папки и ленты идут в порядке первого появления.
+func New(title string, subs []Subscription, created time.Time) *Document {
+	doc := &Document{
+		Version: "2.0",
+		Head:    Head{Title: title, DateCreated: created.Format(time.RFC1123Z)},
+	}
+	for _, sub := range subs {
+		outlines := &doc.Body.Outlines
+		if sub.Category != "" {
+			for _, name := range strings.Split(sub.Category, "/") {
+				outlines = &folder(outlines, name).Outlines
+			}
+		}
+		text := sub.Title
+		if text == "" {
+			text = sub.URL
+		}
+		*outlines = append(*outlines, Outline{Text: text, Title: text, Type: "rss", XMLURL: sub.URL, HTMLURL: sub.SiteLink})
+	}
+	return doc
+}
+
+// folder возвращает папку name среди outlines, создавая её при необходимости.
+func folder(outlines *[]Outline, name string) *Outline {
+	for i := range *outlines {
+		if o := &(*outlines)[i]; o.XMLURL == "" && o.Text == name {
+			return o
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1]
+}
+
+// Write записывает документ в w с XML-декларацией.
+func (doc *Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode OPML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,179 @@
+package opml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"goNews/pkg/db/memory"
+)
+
+// TestParse проверяет разбор подписок и папок из списков разных ридеров
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		expected []Subscription
+		wantErr  bool
+	}{
+		{
+			name: "Nested folders",
+			doc: `<?xml version="1.0" encoding="UTF-8"?>
+				<opml version="2.0"><head><title>Subscriptions</title></head><body>
+					<outline text="Go blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog"/>
+					<outline text="Tech">
+						<outline text="Habr" title="Хабр" type="rss" xmlUrl=" https://habr.com/ru/rss/all/ "/>
+						<outline title="Go">
+							<outline title="Golang Weekly" xmlUrl="https://golangweekly.com/rss"/>
+						</outline>
+					</outline>
+					<outline text="Empty folder"/>
+				</body></opml>`,
+			expected: []Subscription{
+				{URL: "https://go.dev/blog/feed.atom", Title: "Go blog", SiteLink: "https://go.dev/blog"},
+				{URL: "https://habr.com/ru/rss/all/", Title: "Habr", Category: "Tech"},
+				{URL: "https://golangweekly.com/rss", Title: "Golang Weekly", Category: "Tech/Go"},
+			},
+		},
+		{
+			name: "OPML 1.0 with windows-1251",
+			doc: "<?xml version=\"1.0\" encoding=\"windows-1251\"?>" +
+				"<opml version=\"1.0\"><body><outline text=\"\xcd\xee\xe2\xee\xf1\xf2\xe8\" xmlUrl=\"http://example.com/rss\"/></body></opml>",
+			expected: []Subscription{{URL: "http://example.com/rss", Title: "Новости"}},
+		},
+		{
+			name:     "No subscriptions",
+			doc:      `<opml version="2.0"><head/><body/></opml>`,
+			expected: []Subscription{},
+		},
+		{
+			name:    "Not OPML",
+			doc:     `<rss version="2.0"><channel/></rss>`,
+			wantErr: true,
+		},
+		{
+			name:    "Broken XML",
+			doc:     `<opml version="2.0"><body><outline`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subs, err := Parse(strings.NewReader(tt.doc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(subs, tt.expected) {
+				t.Errorf("Parse() = %+v, expected %+v", subs, tt.expected)
+			}
+		})
+	}
+}
+
+// TestRoundTrip проверяет, что записанный список читается обратно без потерь
+func TestRoundTrip(t *testing.T) {
+	subs := []Subscription{
+		{URL: "https://go.dev/blog/feed.atom", Title: "Go blog", SiteLink: "https://go.dev/blog"},
+		{URL: "https://habr.com/ru/rss/all/", Title: "Habr & Co", Category: "Tech"},
+		{URL: "https://golangweekly.com/rss", Title: "Golang Weekly", Category: "Tech/Go"},
+		{URL: "https://example.com/rss", Title: "Example", Category: "Tech/Go"},
+	}
+	var buf bytes.Buffer
+	if err := New("goNews", subs, time.Now()).Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed, subs) {
+		t.Errorf("Parse() = %+v, expected %+v", parsed, subs)
+	}
+}
+
+// TestImport проверяет, что повторный импорт не создаёт лент и не меняет их
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	existing, err := store.AddFeed(ctx, "http://example.com/rss")
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+
+	subs := []Subscription{
+		{URL: "http://example.com/rss", Title: "Example", Category: "News"},
+		{URL: "https://go.dev/blog/feed.atom", Title: "Go blog", Category: "Tech"},
+		{URL: "ftp://example.com/feed", Title: "FTP"},
+	}
+	result, err := Import(ctx, store, subs)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Added) != 1 || len(result.Existing) != 1 || len(result.Errors) != 1 {
+		t.Errorf("Unexpected first import result: %+v", result)
+	}
+
+	// Папка известной ленты уже задана и не перезаписывается
+	subs[0].Category = "Other"
+	result, err = Import(ctx, store, subs)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Added) != 0 || len(result.Existing) != 2 {
+		t.Errorf("Unexpected second import result: %+v", result)
+	}
+
+	exported, err := Export(ctx, store)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	expected := []Subscription{
+		{URL: existing.URL, Category: "News"},
+		{URL: "https://go.dev/blog/feed.atom", Title: "Go blog", Category: "Tech"},
+	}
+	if !reflect.DeepEqual(exported, expected) {
+		t.Errorf("Export() = %+v, expected %+v", exported, expected)
+	}
+}
+
+// TestImportTitle проверяет, что название из списка не затирается
+// заголовком канала, который поллер сохраняет при опросе
+func TestImportTitle(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	subs := []Subscription{
+		{URL: "https://go.dev/blog/feed.atom", Title: "Go blog"},
+		{URL: "https://habr.com/ru/rss/all/"},
+	}
+	if _, err := Import(ctx, store, subs); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	feeds, err := store.Feeds(ctx)
+	if err != nil {
+		t.Fatalf("Feeds() error = %v", err)
+	}
+	for i, f := range feeds {
+		f.Title = fmt.Sprintf("Channel %d", i)
+		if err := store.UpdateFeedMeta(ctx, f); err != nil {
+			t.Fatalf("UpdateFeedMeta() error = %v", err)
+		}
+	}
+
+	exported, err := Export(ctx, store)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	expected := []Subscription{
+		{URL: "https://go.dev/blog/feed.atom", Title: "Go blog"},
+		{URL: "https://habr.com/ru/rss/all/", Title: "Channel 1"},
+	}
+	if !reflect.DeepEqual(exported, expected) {
+		t.Errorf("Export() = %+v, expected %+v", exported, expected)
+	}
+}
@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const nsAtom = "http://www.w3.org/2005/Atom"
+
+// parseAtom разбирает Atom 1.0 документ, начиная с содержимого корневого <feed>.
+func parseAtom(d *xml.Decoder) (*Feed, error) {
+	feed := &Feed{Format: FormatAtom}
+	index := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return feed, fmt.Errorf("xml error: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return feed, nil
+		case xml.StartElement:
+			if !isAtom(t.Name) {
+				if err := d.Skip(); err != nil {
+					return feed, fmt.Errorf("xml error: %w", err)
+				}
+				continue
+			}
+			switch t.Name.Local {
+			case "title":
+				err = decodeAtomText(d, t, &feed.Title)
+			case "subtitle":
+				err = decodeAtomText(d, t, &feed.Description)
+			case "icon", "logo":
+				// Квадратная иконка предпочтительнее широкого логотипа
+				var image string
+				if err = decodeText(d, t, &image); err == nil && (feed.Image == "" || t.Name.Local == "icon") {
+					feed.Image = image
+				}
+			case "link":
+				if href, ok := alternateLink(t); ok && feed.Link == "" {
+					feed.Link = href
+				}
+				err = d.Skip()
+			case "entry":
+				var item Item
+				item, err = parseAtomEntry(d)
+				if err == nil {
+					feed.add(index, item)
+				}
+				index++
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return feed, fmt.Errorf("xml error: %w", err)
+			}
+		}
+	}
+}
+
+func parseAtomEntry(d *xml.Decoder) (Item, error) {
+	var item Item
+	var published, updated string
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return item, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			// Дата первой публикации важнее даты последней правки
+			item.PubDate = published
+			if item.PubDate == "" {
+				item.PubDate = updated
+			}
+			return item, nil
+		case xml.StartElement:
+			if !isAtom(t.Name) {
+				if err := d.Skip(); err != nil {
+					return item, err
+				}
+				continue
+			}
+			switch t.Name.Local {
+			case "title":
+				err = decodeAtomText(d, t, &item.Title)
+				// Заголовок храним простым текстом, даже если лента отдала HTML
+				if typ := attr(t, "type"); err == nil && typ != "" && typ != "text" {
+					item.Title = Text(item.Title)
+				}
+			case "id":
+				err = decodeText(d, t, &item.GUID)
+			case "link":
+				if href, ok := alternateLink(t); ok && item.Link == "" {
+					item.Link = href
+				}
+				err = d.Skip()
+			case "published":
+				err = decodeText(d, t, &published)
+			case "updated":
+				err = decodeText(d, t, &updated)
+			case "summary":
+				err = decodeAtomText(d, t, &item.Description)
+			case "content":
+				err = decodeAtomText(d, t, &item.Content)
+			case "author":
+				var author struct {
+					Name string `xml:"name"`
+				}
+				if err = d.DecodeElement(&author, &t); err == nil && item.Author == "" {
+					item.Author = strings.TrimSpace(author.Name)
+				}
+			case "category":
+				if term := attr(t, "term"); term != "" {
+					item.Categories = append(item.Categories, term)
+				}
+				err = d.Skip()
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return item, err
+			}
+		}
+	}
+}
+
+// decodeAtomText читает Atom-конструкцию текста. Для type="xhtml"
+// возвращается разметка внутри обёртки, для text и html - текст элемента.
+func decodeAtomText(d *xml.Decoder, start xml.StartElement, dst *string) error {
+	if attr(start, "type") != "xhtml" {
+		return decodeText(d, start, dst)
+	}
+	var inner struct {
+		XML string `xml:",innerxml"`
+	}
+	if err := d.DecodeElement(&inner, &start); err != nil {
+		return err
+	}
+	s := strings.TrimSpace(inner.XML)
+	if strings.HasPrefix(s, "<div") && strings.HasSuffix(s, "</div>") {
+		if i := strings.Index(s, ">"); i >= 0 {
+			s = s[i+1 : len(s)-len("</div>")]
+		}
+	}
+	*dst = strings.TrimSpace(s)
+	return nil
+}
+
+// alternateLink возвращает href ссылки на HTML-версию: rel="alternate" или rel не указан.
+func alternateLink(start xml.StartElement) (string, bool) {
+	rel := attr(start, "rel")
+	if rel != "" && rel != "alternate" {
+		return "", false
+	}
+	href := strings.TrimSpace(attr(start, "href"))
+	return href, href != ""
+}
+
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// isAtom допускает и документы без объявленного пространства имён.
+func isAtom(name xml.Name) bool {
+	return name.Space == nsAtom || name.Space == ""
+}
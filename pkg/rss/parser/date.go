@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrBadDate = errors.New("unrecognized date format")
+
+// Форматы дат, встречающиеся в лентах. Названия зон заранее заменяются
+// на смещения (см. zoneOffsets), поэтому все RFC822-подобные форматы
+// записаны с числовой зоной.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+	"Mon, 2 Jan 06 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04:05 -07:00",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Monday, 2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05-07:00",
+}
+
+// Форматы без зоны считаются временем в UTC.
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Mon, 2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04:05",
+}
+
+// zoneOffsets - буквенные зоны, которые time.Parse не знает или
+// интерпретирует с нулевым смещением.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"MSD":  "+0400",
+	"SAMT": "+0400",
+	"YEKT": "+0500",
+	"OMST": "+0600",
+	"KRAT": "+0700",
+	"IRKT": "+0800",
+	"YAKT": "+0900",
+	"VLAT": "+1000",
+	"IST":  "+0530",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+}
+
+// ParseDate разбирает дату публикации в любом из распространённых в лентах
+// форматов: RFC1123/RFC1123Z, RFC3339, RFC822 с двузначным годом и
+// буквенными зонами вроде MSK.
+func ParseDate(s string) (time.Time, error) {
+	s = normalizeDate(s)
+	if s == "" {
+		return time.Time{}, ErrBadDate
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrBadDate
+}
+
+// normalizeDate схлопывает пробелы и заменяет буквенную зону на смещение.
+func normalizeDate(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	last := fields[len(fields)-1]
+	if offset, ok := zoneOffsets[strings.ToUpper(last)]; ok && len(fields) > 1 {
+		fields[len(fields)-1] = offset
+	}
+	// Часть лент пишет день недели без запятой: "Mon 02 Jan 2006 ..."
+	if len(fields[0]) == 3 && !strings.HasSuffix(fields[0], ",") && isWeekday(fields[0]) {
+		fields[0] += ","
+	}
+	return strings.Join(fields, " ")
+}
+
+func isWeekday(s string) bool {
+	switch strings.ToLower(s) {
+	case "mon", "tue", "wed", "thu", "fri", "sat", "sun":
+		return true
+	}
+	return false
+}
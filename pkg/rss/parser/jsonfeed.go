@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+type jsonFeed struct {
+	Version     string            `json:"version"`
+	Title       string            `json:"title"`
+	HomePageURL string            `json:"home_page_url"`
+	Description string            `json:"description"`
+	Language    string            `json:"language"`
+	Icon        string            `json:"icon"`
+	Favicon     string            `json:"favicon"`
+	Items       []json.RawMessage `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonItem struct {
+	ID            json.RawMessage `json:"id"`
+	URL           string          `json:"url"`
+	ExternalURL   string          `json:"external_url"`
+	Title         string          `json:"title"`
+	ContentHTML   string          `json:"content_html"`
+	ContentText   string          `json:"content_text"`
+	Summary       string          `json:"summary"`
+	DatePublished string          `json:"date_published"`
+	DateModified  string          `json:"date_modified"`
+	Authors       []jsonAuthor    `json:"authors"`
+	// author - поле версии 1.0, в 1.1 заменено на authors
+	Author *jsonAuthor `json:"author"`
+	Tags   []string    `json:"tags"`
+}
+
+// parseJSONFeed разбирает JSON Feed 1.0/1.1. Записи декодируются по одной,
+// чтобы одна испорченная запись не теряла всю ленту.
+func parseJSONFeed(r io.Reader) (*Feed, error) {
+	var doc jsonFeed
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	if !strings.HasPrefix(doc.Version, jsonFeedVersionPrefix) {
+		return nil, fmt.Errorf("%w: json document without jsonfeed version", ErrUnknownFormat)
+	}
+
+	feed := &Feed{
+		Format:      FormatJSON,
+		Title:       strings.TrimSpace(doc.Title),
+		Link:        doc.HomePageURL,
+		Description: strings.TrimSpace(doc.Description),
+		Language:    doc.Language,
+		Image:       doc.Favicon,
+	}
+	if feed.Image == "" {
+		feed.Image = doc.Icon
+	}
+	for i, raw := range doc.Items {
+		var ji jsonItem
+		if err := json.Unmarshal(raw, &ji); err != nil {
+			feed.Errors = append(feed.Errors, &ItemError{Index: i, Err: err})
+			continue
+		}
+
+		item := Item{
+			GUID:        jsonID(ji.ID),
+			Link:        ji.URL,
+			Title:       strings.TrimSpace(ji.Title),
+			Description: strings.TrimSpace(ji.Summary),
+			Content:     ji.ContentHTML,
+			PubDate:     ji.DatePublished,
+			Categories:  ji.Tags,
+		}
+		if item.Link == "" {
+			item.Link = ji.ExternalURL
+		}
+		if item.Description == "" {
+			item.Description = strings.TrimSpace(ji.ContentText)
+		}
+		if item.Content == "" {
+			item.Content = ji.ContentText
+		}
+		if item.PubDate == "" {
+			item.PubDate = ji.DateModified
+		}
+		if len(ji.Authors) > 0 {
+			item.Author = ji.Authors[0].Name
+		} else if ji.Author != nil {
+			item.Author = ji.Author.Name
+		}
+
+		feed.add(i, item)
+	}
+	return feed, nil
+}
+
+// jsonID приводит id записи к строке: в старых лентах встречаются числа.
+func jsonID(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if id := string(bytes.TrimSpace(raw)); id != "null" {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,359 @@
+// Package parser разбирает ленты новостей в типизированные структуры.
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang.org/x/text/encoding/htmlindex"
+	"html"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Пространства имён расширений, которые встречаются в лентах.
+const (
+	nsContent = "http://purl.org/rss/1.0/modules/content/"
+	nsDC      = "http://purl.org/dc/elements/1.1/"
+)
+
+// Форматы лент, которые умеет разбирать пакет.
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+	FormatRDF  = "rdf"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown feed format")
+	ErrNoChannel     = errors.New("rss document has no channel")
+	ErrEmptyItem     = errors.New("item has no title, description or content")
+)
+
+// Feed - разобранная лента.
+type Feed struct {
+	Format      string
+	Title       string
+	Link        string
+	Description string
+	Language    string
+	// Image - адрес логотипа или иконки канала.
+	Image string
+	// TTL - интервал обновления, объявленный каналом в <ttl> или
+	// sy:updatePeriod. Ноль, если канал его не указал.
+	TTL time.Duration
+	// SkipHours (по GMT) и SkipDays - когда канал просит его не опрашивать.
+	SkipHours []int
+	SkipDays  []time.Weekday
+	Items     []Item
+	// Errors содержит ошибки отдельных элементов, которые не попали в Items.
+	Errors []error
+}
+
+// Item - отдельная запись ленты.
+type Item struct {
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	GUID        string
+	PubDate     string
+	// Published - разобранная PubDate, нулевое значение если дату
+	// не удалось распознать.
+	Published  time.Time
+	Author     string
+	Categories []string
+}
+
+// ItemError описывает ошибку разбора одного элемента ленты.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// Parse потоково разбирает документ ленты, определяя формат по содержимому:
+// JSON Feed узнаётся по первому символу, XML-форматы - по корневому элементу.
+// При синтаксической ошибке в середине документа возвращается уже
+// разобранная часть вместе с ошибкой.
+func Parse(r io.Reader) (*Feed, error) {
+	return ParseWithType(r, "")
+}
+
+// ParseWithType разбирает ленту с учётом Content-Type ответа сервера.
+func ParseWithType(r io.Reader, contentType string) (*Feed, error) {
+	br := bufio.NewReader(r)
+	if isJSONType(contentType) || sniffJSON(br) {
+		// BOM XML-декодер пропускает сам, а encoding/json считает ошибкой
+		if bom, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+			br.Discard(len(utf8BOM))
+		}
+		return parseJSONFeed(br)
+	}
+
+	d := newDecoder(br)
+
+	root, err := rootElement(d)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root.Name.Local {
+	case "rss":
+		return parseRSS(d)
+	case "feed":
+		return parseAtom(d)
+	case "RDF":
+		return parseRDF(d)
+	default:
+		return nil, fmt.Errorf("%w: <%s>", ErrUnknownFormat, root.Name.Local)
+	}
+}
+
+func isJSONType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/feed+json"
+}
+
+// utf8BOM - метка порядка байтов, которой некоторые серверы начинают UTF-8.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// sniffJSON проверяет первый значимый символ документа, не потребляя его.
+func sniffJSON(br *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil {
+			return false
+		}
+		switch c := b[i-1]; c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case 0xEF, 0xBB, 0xBF:
+			// BOM UTF-8
+			continue
+		default:
+			return c == '{'
+		}
+	}
+}
+
+func newDecoder(r io.Reader) *xml.Decoder {
+	d := xml.NewDecoder(r)
+	// В реальных лентах часто встречаются HTML-сущности вроде &nbsp;
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported charset %q: %w", label, err)
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+	return d
+}
+
+func rootElement(d *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return xml.StartElement{}, fmt.Errorf("%w: empty document", ErrUnknownFormat)
+			}
+			return xml.StartElement{}, fmt.Errorf("xml error: %w", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se, nil
+		}
+	}
+}
+
+func parseRSS(d *xml.Decoder) (*Feed, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, ErrNoChannel
+			}
+			return nil, fmt.Errorf("xml error: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if se.Name.Local != "channel" || se.Name.Space != "" {
+			if err := d.Skip(); err != nil {
+				return nil, fmt.Errorf("xml error: %w", err)
+			}
+			continue
+		}
+
+		feed := &Feed{Format: FormatRSS}
+		if err := parseChannel(d, feed); err != nil {
+			return feed, err
+		}
+		return feed, nil
+	}
+}
+
+func parseChannel(d *xml.Decoder, feed *Feed) error {
+	index := 0
+	var sy syndication
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return fmt.Errorf("xml error: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			sy.apply(feed)
+			return nil
+		case xml.StartElement:
+			if t.Name.Space == nsSy {
+				if err := sy.decode(d, t); err != nil {
+					return fmt.Errorf("xml error: %w", err)
+				}
+				continue
+			}
+			if t.Name.Space != "" {
+				if err := d.Skip(); err != nil {
+					return fmt.Errorf("xml error: %w", err)
+				}
+				continue
+			}
+			switch t.Name.Local {
+			case "title":
+				err = decodeText(d, t, &feed.Title)
+			case "link":
+				err = decodeText(d, t, &feed.Link)
+			case "description":
+				err = decodeText(d, t, &feed.Description)
+			case "language":
+				err = decodeText(d, t, &feed.Language)
+			case "image":
+				err = decodeImage(d, t, &feed.Image)
+			case "ttl":
+				err = decodeTTL(d, t, &feed.TTL)
+			case "skipHours":
+				err = decodeSkipHours(d, t, &feed.SkipHours)
+			case "skipDays":
+				err = decodeSkipDays(d, t, &feed.SkipDays)
+			case "item":
+				var item Item
+				item, err = parseRSSItem(d)
+				if err == nil {
+					feed.add(index, item)
+				}
+				index++
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return fmt.Errorf("xml error: %w", err)
+			}
+		}
+	}
+}
+
+func parseRSSItem(d *xml.Decoder) (Item, error) {
+	var item Item
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return item, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return item, nil
+		case xml.StartElement:
+			switch t.Name.Space + " " + t.Name.Local {
+			case " title":
+				err = decodeText(d, t, &item.Title)
+			case " link":
+				err = decodeText(d, t, &item.Link)
+			case " description":
+				err = decodeText(d, t, &item.Description)
+			case " guid":
+				err = decodeText(d, t, &item.GUID)
+			case " pubDate":
+				err = decodeText(d, t, &item.PubDate)
+			case " author", nsDC + " creator":
+				err = decodeText(d, t, &item.Author)
+			case nsDC + " date":
+				if item.PubDate == "" {
+					err = decodeText(d, t, &item.PubDate)
+				} else {
+					err = d.Skip()
+				}
+			case nsContent + " encoded":
+				err = decodeText(d, t, &item.Content)
+			case " category":
+				var category string
+				if err = decodeText(d, t, &category); err == nil && category != "" {
+					item.Categories = append(item.Categories, category)
+				}
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return item, err
+			}
+		}
+	}
+}
+
+// decodeText читает текстовое содержимое элемента, включая CDATA и сущности.
+func decodeText(d *xml.Decoder, start xml.StartElement, dst *string) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	*dst = strings.TrimSpace(s)
+	return nil
+}
+
+// decodeImage читает адрес картинки из <image><url>...</url></image>.
+func decodeImage(d *xml.Decoder, start xml.StartElement, dst *string) error {
+	var image struct {
+		URL string `xml:"url"`
+	}
+	if err := d.DecodeElement(&image, &start); err != nil {
+		return err
+	}
+	*dst = strings.TrimSpace(image.URL)
+	return nil
+}
+
+// add проверяет запись и добавляет её в ленту либо в список ошибок.
+func (f *Feed) add(index int, item Item) {
+	if item.Title == "" && item.Description == "" && item.Content == "" {
+		f.Errors = append(f.Errors, &ItemError{Index: index, Err: ErrEmptyItem})
+		return
+	}
+	if item.PubDate != "" {
+		item.Published, _ = ParseDate(item.PubDate)
+	}
+	f.Items = append(f.Items, item)
+}
+
+var reTags = regexp.MustCompile(`(?s)<.*?>`)
+
+// Text превращает HTML-фрагмент в простой текст.
+func Text(s string) string {
+	return strings.TrimSpace(html.UnescapeString(reTags.ReplaceAllString(s, "")))
+}
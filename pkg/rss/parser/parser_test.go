@@ -0,0 +1,403 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParse проверяет разбор RSS 2.0 документов
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		expectError error
+		items       []Item
+		itemErrors  int
+	}{
+		{
+			name: "Plain and CDATA titles",
+			doc: `<rss version="2.0"><channel><title>Feed</title>
+				<item><title>Plain title</title><description>Plain</description></item>
+				<item><title><![CDATA[CDATA title]]></title><description><![CDATA[<b>bold</b>]]></description></item>
+			</channel></rss>`,
+			items: []Item{
+				{Title: "Plain title", Description: "Plain"},
+				{Title: "CDATA title", Description: "<b>bold</b>"},
+			},
+		},
+		{
+			name: "Entities and attributes on item",
+			doc: `<rss version="2.0"><channel>
+				<item id="1" xml:lang="ru"><title>Tom &amp; Jerry&nbsp;&mdash; 2</title><guid isPermaLink="false">a-1</guid></item>
+			</channel></rss>`,
+			items: []Item{
+				{Title: "Tom & Jerry — 2", GUID: "a-1"},
+			},
+		},
+		{
+			name: "Namespaced elements",
+			doc: `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"
+				xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:media="http://search.yahoo.com/mrss/">
+				<channel><item>
+					<title>Real title</title>
+					<media:title>Media title</media:title>
+					<dc:creator>Author</dc:creator>
+					<dc:date>2024-01-02T03:04:05Z</dc:date>
+					<content:encoded><![CDATA[<p>Full</p>]]></content:encoded>
+				</item></channel></rss>`,
+			items: []Item{
+				{Title: "Real title", Author: "Author", PubDate: "2024-01-02T03:04:05Z", Content: "<p>Full</p>"},
+			},
+		},
+		{
+			name: "Empty item reported as item error",
+			doc: `<rss version="2.0"><channel>
+				<item><link>http://example.com/1</link></item>
+				<item><title>Kept</title></item>
+			</channel></rss>`,
+			items:      []Item{{Title: "Kept"}},
+			itemErrors: 1,
+		},
+		{
+			name:        "Unknown root element",
+			doc:         `<html><body>not a feed</body></html>`,
+			expectError: ErrUnknownFormat,
+		},
+		{
+			name:        "RSS without channel",
+			doc:         `<rss version="2.0"></rss>`,
+			expectError: ErrNoChannel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := Parse(strings.NewReader(tt.doc))
+			if tt.expectError != nil {
+				if !errors.Is(err, tt.expectError) {
+					t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(feed.Items) != len(tt.items) {
+				t.Fatalf("Expected %d items, got %d: %+v", len(tt.items), len(feed.Items), feed.Items)
+			}
+			for i, exp := range tt.items {
+				got := feed.Items[i]
+				if got.Title != exp.Title || got.Description != exp.Description || got.GUID != exp.GUID ||
+					got.Author != exp.Author || got.PubDate != exp.PubDate || got.Content != exp.Content {
+					t.Errorf("Item %d: expected %+v, got %+v", i, exp, got)
+				}
+			}
+			if len(feed.Errors) != tt.itemErrors {
+				t.Errorf("Expected %d item errors, got %v", tt.itemErrors, feed.Errors)
+			}
+			for _, itemErr := range feed.Errors {
+				var ie *ItemError
+				if !errors.As(itemErr, &ie) {
+					t.Errorf("Expected *ItemError, got %T", itemErr)
+				}
+			}
+		})
+	}
+}
+
+// TestParseTruncated проверяет, что оборванная лента отдаёт уже разобранные записи
+func TestParseTruncated(t *testing.T) {
+	doc := `<rss version="2.0"><channel><item><title>First</title></item><item><title>Sec`
+	feed, err := Parse(strings.NewReader(doc))
+	if err == nil {
+		t.Fatalf("Expected error for truncated document")
+	}
+	if feed == nil || len(feed.Items) != 1 || feed.Items[0].Title != "First" {
+		t.Errorf("Expected the first item to be kept, got %+v", feed)
+	}
+}
+
+// TestParseAtom проверяет разбор Atom 1.0 документов
+func TestParseAtom(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+	<feed xmlns="http://www.w3.org/2005/Atom">
+		<title>Atom Feed</title>
+		<subtitle>Sub</subtitle>
+		<link rel="self" href="http://example.com/feed.atom"/>
+		<link href="http://example.com/"/>
+		<logo>http://example.com/logo.png</logo>
+		<icon>http://example.com/favicon.ico</icon>
+		<entry>
+			<title type="html">A &amp;amp; B</title>
+			<id>urn:uuid:1</id>
+			<link rel="edit" href="http://example.com/edit/1"/>
+			<link rel="alternate" href="http://example.com/1"/>
+			<updated>2024-02-01T00:00:00Z</updated>
+			<published>2024-01-01T00:00:00Z</published>
+			<summary>Summary</summary>
+			<content type="html">&lt;p&gt;Content&lt;/p&gt;</content>
+			<author><name>Author</name></author>
+		</entry>
+		<entry>
+			<title>Only updated</title>
+			<id>urn:uuid:2</id>
+			<updated>2024-03-01T00:00:00Z</updated>
+			<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>X</p></div></content>
+		</entry>
+		<entry><id>urn:uuid:3</id></entry>
+	</feed>`
+
+	feed, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if feed.Format != FormatAtom {
+		t.Errorf("Expected format %q, got %q", FormatAtom, feed.Format)
+	}
+	if feed.Title != "Atom Feed" || feed.Description != "Sub" || feed.Link != "http://example.com/" ||
+		feed.Image != "http://example.com/favicon.ico" {
+		t.Errorf("Unexpected feed metadata: %+v", feed)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(feed.Items))
+	}
+	if len(feed.Errors) != 1 {
+		t.Errorf("Expected 1 item error, got %v", feed.Errors)
+	}
+
+	expected := []Item{
+		{Title: "A & B", GUID: "urn:uuid:1", Link: "http://example.com/1", PubDate: "2024-01-01T00:00:00Z",
+			Description: "Summary", Content: "<p>Content</p>", Author: "Author"},
+		{Title: "Only updated", GUID: "urn:uuid:2", PubDate: "2024-03-01T00:00:00Z", Content: "<p>X</p>"},
+	}
+	for i, exp := range expected {
+		got := feed.Items[i]
+		if got.Title != exp.Title || got.GUID != exp.GUID || got.Link != exp.Link || got.PubDate != exp.PubDate ||
+			got.Description != exp.Description || got.Content != exp.Content || got.Author != exp.Author {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, exp, got)
+		}
+	}
+}
+
+// TestParseJSONFeed проверяет разбор JSON Feed и определение формата
+func TestParseJSONFeed(t *testing.T) {
+	doc := `
+	{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "JSON Feed",
+		"home_page_url": "http://example.com/",
+		"items": [
+			{"id": "1", "url": "http://example.com/1", "title": "First", "content_html": "<p>Hi</p>",
+			 "summary": "Short", "date_published": "2024-01-01T00:00:00Z", "authors": [{"name": "Author"}]},
+			{"id": 2, "external_url": "http://other.com/2", "content_text": "Text only", "date_modified": "2024-01-02T00:00:00Z"},
+			{"id": "3"},
+			{"id": "4", "title": 42}
+		]
+	}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		bom         bool
+	}{
+		{name: "Sniffed without content type", contentType: ""},
+		{name: "Declared content type", contentType: "application/feed+json; charset=utf-8"},
+		{name: "Sniffed with BOM", contentType: "", bom: true},
+		{name: "Declared content type with BOM", contentType: "application/json", bom: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := doc
+			if tt.bom {
+				src = "\ufeff" + src
+			}
+			feed, err := ParseWithType(strings.NewReader(src), tt.contentType)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if feed.Format != FormatJSON || feed.Title != "JSON Feed" || feed.Link != "http://example.com/" {
+				t.Errorf("Unexpected feed metadata: %+v", feed)
+			}
+			if len(feed.Items) != 2 {
+				t.Fatalf("Expected 2 items, got %d", len(feed.Items))
+			}
+			if len(feed.Errors) != 2 {
+				t.Errorf("Expected 2 item errors, got %v", feed.Errors)
+			}
+
+			expected := []Item{
+				{GUID: "1", Link: "http://example.com/1", Title: "First", Description: "Short", Content: "<p>Hi</p>",
+					PubDate: "2024-01-01T00:00:00Z", Author: "Author"},
+				{GUID: "2", Link: "http://other.com/2", Description: "Text only", Content: "Text only",
+					PubDate: "2024-01-02T00:00:00Z"},
+			}
+			for i, exp := range expected {
+				got := feed.Items[i]
+				if got.Title != exp.Title || got.GUID != exp.GUID || got.Link != exp.Link || got.PubDate != exp.PubDate ||
+					got.Description != exp.Description || got.Content != exp.Content || got.Author != exp.Author {
+					t.Errorf("Item %d: expected %+v, got %+v", i, exp, got)
+				}
+			}
+		})
+	}
+
+	if _, err := Parse(strings.NewReader(`{"title": "no version"}`)); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat for plain JSON, got %v", err)
+	}
+}
+
+// TestParseRDF проверяет разбор RSS 1.0 (RDF) документов
+func TestParseRDF(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+	<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+		xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+		<channel rdf:about="http://example.com/rss">
+			<title>RDF Feed</title>
+			<link>http://example.com/</link>
+			<description>Old school</description>
+			<items><rdf:Seq><rdf:li rdf:resource="http://example.com/1"/></rdf:Seq></items>
+		</channel>
+		<item rdf:about="http://example.com/1">
+			<title>First</title>
+			<link>http://example.com/1</link>
+			<description>Desc</description>
+			<dc:date>2024-01-01T10:00:00+03:00</dc:date>
+			<dc:creator>Author</dc:creator>
+		</item>
+		<item rdf:about="http://example.com/2"><link>http://example.com/2</link></item>
+	</rdf:RDF>`
+
+	feed, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if feed.Format != FormatRDF || feed.Title != "RDF Feed" || feed.Link != "http://example.com/" || feed.Description != "Old school" {
+		t.Errorf("Unexpected feed metadata: %+v", feed)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(feed.Items))
+	}
+	if len(feed.Errors) != 1 {
+		t.Errorf("Expected 1 item error, got %v", feed.Errors)
+	}
+	exp := Item{GUID: "http://example.com/1", Link: "http://example.com/1", Title: "First", Description: "Desc",
+		PubDate: "2024-01-01T10:00:00+03:00", Author: "Author"}
+	got := feed.Items[0]
+	if got.Title != exp.Title || got.GUID != exp.GUID || got.Link != exp.Link || got.PubDate != exp.PubDate ||
+		got.Description != exp.Description || got.Author != exp.Author {
+		t.Errorf("Expected %+v, got %+v", exp, got)
+	}
+}
+
+// TestParseDate проверяет разбор дат публикации в разных форматах
+func TestParseDate(t *testing.T) {
+	msk := time.FixedZone("", 3*60*60)
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Time
+		hasError bool
+	}{
+		{name: "RFC1123 GMT", input: "Mon, 01 Jan 2023 00:00:00 GMT", expected: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "RFC1123Z", input: "Tue, 13 Aug 2024 19:00:00 +0300", expected: time.Date(2024, 8, 13, 19, 0, 0, 0, msk)},
+		{name: "Single digit day", input: "Fri, 4 Oct 2024 08:30:00 +0000", expected: time.Date(2024, 10, 4, 8, 30, 0, 0, time.UTC)},
+		{name: "Named zone MSK", input: "Tue, 13 Aug 2024 19:00:00 MSK", expected: time.Date(2024, 8, 13, 19, 0, 0, 0, msk)},
+		{name: "Named zone EST", input: "Wed, 02 Oct 2002 08:00:00 EST", expected: time.Date(2002, 10, 2, 13, 0, 0, 0, time.UTC)},
+		{name: "RFC822 two digit year", input: "02 Oct 02 13:00 GMT", expected: time.Date(2002, 10, 2, 13, 0, 0, 0, time.UTC)},
+		{name: "RFC822 with weekday", input: "Wed, 02 Oct 02 15:00:00 +0200", expected: time.Date(2002, 10, 2, 13, 0, 0, 0, time.UTC)},
+		{name: "RFC3339", input: "2024-01-01T10:00:00+03:00", expected: time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)},
+		{name: "RFC3339 fractional", input: "2024-01-01T10:00:00.123Z", expected: time.Date(2024, 1, 1, 10, 0, 0, 123000000, time.UTC)},
+		{name: "Date only", input: "2023-01-02", expected: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "Extra whitespace", input: "  Mon,  01 Jan 2023   00:00:00  GMT ", expected: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "Garbage", input: "yesterday", hasError: true},
+		{name: "Empty", input: "", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDate(tt.input)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("Expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestParseSchedule проверяет разбор подсказок о частоте обновления канала
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		ttl       time.Duration
+		skipHours []int
+		skipDays  []time.Weekday
+	}{
+		{
+			name: "ttl and skip lists",
+			doc: `<rss version="2.0"><channel><title>T</title><ttl>90</ttl>
+				<skipHours><hour>0</hour><hour>24</hour><hour>5</hour><hour>x</hour></skipHours>
+				<skipDays><day>Saturday</day><day>sunday</day><day>Someday</day></skipDays>
+			</channel></rss>`,
+			ttl:       90 * time.Minute,
+			skipHours: []int{0, 0, 5},
+			skipDays:  []time.Weekday{time.Saturday, time.Sunday},
+		},
+		{
+			name: "syndication module",
+			doc: `<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+				<sy:updateFrequency>4</sy:updateFrequency><sy:updatePeriod>daily</sy:updatePeriod>
+			</channel></rss>`,
+			ttl: 6 * time.Hour,
+		},
+		{
+			name: "ttl wins over syndication",
+			doc: `<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+				<sy:updatePeriod>hourly</sy:updatePeriod><ttl>15</ttl>
+			</channel></rss>`,
+			ttl: 15 * time.Minute,
+		},
+		{
+			name: "rdf syndication without frequency",
+			doc: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"
+				xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+				<channel><title>R</title><sy:updatePeriod>weekly</sy:updatePeriod></channel>
+			</rdf:RDF>`,
+			ttl: 7 * 24 * time.Hour,
+		},
+		{
+			name: "no hints",
+			doc:  `<rss version="2.0"><channel><title>T</title><ttl>0</ttl></channel></rss>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := Parse(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if feed.TTL != tt.ttl {
+				t.Errorf("Expected TTL %v, got %v", tt.ttl, feed.TTL)
+			}
+			if fmt.Sprint(feed.SkipHours) != fmt.Sprint(tt.skipHours) {
+				t.Errorf("Expected skip hours %v, got %v", tt.skipHours, feed.SkipHours)
+			}
+			if fmt.Sprint(feed.SkipDays) != fmt.Sprint(tt.skipDays) {
+				t.Errorf("Expected skip days %v, got %v", tt.skipDays, feed.SkipDays)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+const nsRSS1 = "http://purl.org/rss/1.0/"
+
+// parseRDF разбирает RSS 1.0 (RDF). В отличие от RSS 2.0 элементы item
+// лежат рядом с channel, а даты публикации берутся из Dublin Core.
+func parseRDF(d *xml.Decoder) (*Feed, error) {
+	feed := &Feed{Format: FormatRDF}
+	index := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return feed, fmt.Errorf("xml error: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return feed, nil
+		case xml.StartElement:
+			if !isRSS1(t.Name) {
+				if err := d.Skip(); err != nil {
+					return feed, fmt.Errorf("xml error: %w", err)
+				}
+				continue
+			}
+			switch t.Name.Local {
+			case "channel":
+				err = parseRDFChannel(d, feed)
+			case "image":
+				err = decodeImage(d, t, &feed.Image)
+			case "item":
+				var item Item
+				item, err = parseRDFItem(d, t)
+				if err == nil {
+					feed.add(index, item)
+				}
+				index++
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return feed, fmt.Errorf("xml error: %w", err)
+			}
+		}
+	}
+}
+
+func parseRDFChannel(d *xml.Decoder, feed *Feed) error {
+	var sy syndication
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			sy.apply(feed)
+			return nil
+		case xml.StartElement:
+			switch t.Name.Space + " " + t.Name.Local {
+			case nsRSS1 + " title", " title":
+				err = decodeText(d, t, &feed.Title)
+			case nsRSS1 + " link", " link":
+				err = decodeText(d, t, &feed.Link)
+			case nsRSS1 + " description", " description":
+				err = decodeText(d, t, &feed.Description)
+			case nsDC + " language":
+				err = decodeText(d, t, &feed.Language)
+			case nsSy + " updatePeriod", nsSy + " updateFrequency":
+				err = sy.decode(d, t)
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func parseRDFItem(d *xml.Decoder, start xml.StartElement) (Item, error) {
+	// rdf:about - обязательный идентификатор записи в RSS 1.0
+	item := Item{GUID: attr(start, "about")}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return item, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return item, nil
+		case xml.StartElement:
+			switch t.Name.Space + " " + t.Name.Local {
+			case nsRSS1 + " title", " title":
+				err = decodeText(d, t, &item.Title)
+			case nsRSS1 + " link", " link":
+				err = decodeText(d, t, &item.Link)
+			case nsRSS1 + " description", " description":
+				err = decodeText(d, t, &item.Description)
+			case nsDC + " date":
+				err = decodeText(d, t, &item.PubDate)
+			case nsDC + " creator":
+				err = decodeText(d, t, &item.Author)
+			case nsDC + " subject":
+				var subject string
+				if err = decodeText(d, t, &subject); err == nil && subject != "" {
+					item.Categories = append(item.Categories, subject)
+				}
+			case nsContent + " encoded":
+				err = decodeText(d, t, &item.Content)
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return item, err
+			}
+		}
+	}
+}
+
+func isRSS1(name xml.Name) bool {
+	return name.Space == nsRSS1 || name.Space == ""
+}
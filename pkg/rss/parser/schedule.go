@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nsSy - модуль Syndication, в котором канал объявляет частоту обновления.
+const nsSy = "http://purl.org/rss/1.0/modules/syndication/"
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// syndication копит sy:updatePeriod и sy:updateFrequency, которые
+// могут идти в канале в любом порядке.
+type syndication struct {
+	period    string
+	frequency string
+}
+
+func (s *syndication) decode(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "updatePeriod":
+		return decodeText(d, start, &s.period)
+	case "updateFrequency":
+		return decodeText(d, start, &s.frequency)
+	default:
+		return d.Skip()
+	}
+}
+
+// apply задаёт TTL по sy:updatePeriod, если канал не указал <ttl>.
+// sy:updateFrequency - сколько раз за период лента обновляется.
+func (s *syndication) apply(feed *Feed) {
+	period, ok := updatePeriods[strings.ToLower(s.period)]
+	if feed.TTL != 0 || !ok {
+		return
+	}
+	frequency, err := strconv.Atoi(s.frequency)
+	if err != nil || frequency <= 0 {
+		frequency = 1
+	}
+	feed.TTL = period / time.Duration(frequency)
+}
+
+// decodeTTL читает <ttl> - количество минут, на которое ленту можно кэшировать.
+func decodeTTL(d *xml.Decoder, start xml.StartElement, dst *time.Duration) error {
+	var s string
+	if err := decodeText(d, start, &s); err != nil {
+		return err
+	}
+	if minutes, err := strconv.Atoi(s); err == nil && minutes > 0 {
+		*dst = time.Duration(minutes) * time.Minute
+	}
+	return nil
+}
+
+// decodeSkipHours читает <skipHours>. Часы указываются по GMT, 24 встречается
+// вместо 0.
+func decodeSkipHours(d *xml.Decoder, start xml.StartElement, dst *[]int) error {
+	var skip struct {
+		Hours []string `xml:"hour"`
+	}
+	if err := d.DecodeElement(&skip, &start); err != nil {
+		return err
+	}
+	for _, s := range skip.Hours {
+		hour, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		*dst = append(*dst, hour%24)
+	}
+	return nil
+}
+
+// decodeSkipDays читает <skipDays> с английскими названиями дней недели.
+func decodeSkipDays(d *xml.Decoder, start xml.StartElement, dst *[]time.Weekday) error {
+	var skip struct {
+		Days []string `xml:"day"`
+	}
+	if err := d.DecodeElement(&skip, &start); err != nil {
+		return err
+	}
+	for _, s := range skip.Days {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(strings.TrimSpace(s), day.String()) {
+				*dst = append(*dst, day)
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goNews/pkg/db"
+	"goNews/pkg/rss/parser"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Значения по умолчанию для параметров опроса.
+const (
+	defaultPeriod       = 60 * time.Second
+	defaultTick         = time.Second
+	defaultConcurrency  = 4
+	defaultFetchTimeout = 30 * time.Second
+	defaultMaxFailures  = 10
+	defaultShutdown     = 10 * time.Second
+	// maxPending - сколько записей копится до внеочередной вставки,
+	// если загрузки идут без перерыва.
+	maxPending = 500
+	// flushDelay - сколько готовые записи ждут соседние загрузки. Дольше
+	// не ждут: медленный сервер не должен задерживать остальные ленты.
+	flushDelay = 250 * time.Millisecond
+)
+
+// Config - параметры опроса лент.
+type Config struct {
+	// Period - интервал опроса лент, для которых ни пользователь,
+	// ни сам канал не задали свой.
+	Period time.Duration
+	// Tick - как часто проверяется, каким лентам пора обновиться.
+	Tick time.Duration
+	// Concurrency - число одновременных загрузок.
+	Concurrency int
+	// FetchTimeout ограничивает одну загрузку ленты целиком.
+	FetchTimeout time.Duration
+	// MaxFailures - после скольких ошибок подряд лента отключается,
+	// отрицательное значение - не отключать никогда.
+	MaxFailures int
+	// ShutdownTimeout ограничивает ожидание идущих загрузок при остановке.
+	ShutdownTimeout time.Duration
+}
+
+// Poller опрашивает ленты, каждую по своему расписанию, не больше
+// Concurrency одновременно. Одна и та же лента никогда не загружается дважды
+// одновременно: пока она в работе, новые проверки расписания её пропускают.
+type Poller struct {
+	store  db.Store
+	client *http.Client
+
+	mu   sync.RWMutex
+	conf Config
+	// updates передаёт циклу Run новые настройки из Reload.
+	updates chan Config
+}
+
+// fetchResult - итог загрузки. feed несёт новые валидаторы кэша: они
+// сохраняются только вместе с записями.
+type fetchResult struct {
+	feed db.Feed
+	news []db.News
+	err  error
+}
+
+// NewPoller создаёт поллер, подставляя значения по умолчанию.
+func NewPoller(store db.Store, conf Config) *Poller {
+	return &Poller{store: store, conf: withDefaults(conf), client: &http.Client{}, updates: make(chan Config)}
+}
+
+func withDefaults(conf Config) Config {
+	if conf.Period <= 0 {
+		conf.Period = defaultPeriod
+	}
+	if conf.Tick <= 0 {
+		conf.Tick = defaultTick
+	}
+	if conf.Concurrency <= 0 {
+		conf.Concurrency = defaultConcurrency
+	}
+	if conf.FetchTimeout <= 0 {
+		conf.FetchTimeout = defaultFetchTimeout
+	}
+	if conf.MaxFailures == 0 {
+		conf.MaxFailures = defaultMaxFailures
+	}
+	if conf.ShutdownTimeout <= 0 {
+		conf.ShutdownTimeout = defaultShutdown
+	}
+	return conf
+}
+
+// config возвращает текущие настройки: Reload может поменять их,
+// пока идут загрузки.
+func (p *Poller) config() Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.conf
+}
+
+// Reload применяет новые Period, Tick, Concurrency, FetchTimeout и MaxFailures
+// к работающему поллеру. Идущие загрузки не прерываются: если число
+// одновременных загрузок уменьшилось, новые просто не начинаются, пока
+// лишние не закончатся. ShutdownTimeout остаётся прежним.
+func (p *Poller) Reload(ctx context.Context, conf Config) error {
+	select {
+	case p.updates <- withDefaults(conf):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run опрашивает ленты до отмены контекста. Записи из загрузок
+// копятся и сохраняются одной пачкой, когда все запущенные загрузки
+// закончились, записей набралось maxPending или первая из них ждёт
+// дольше flushDelay.
+// После отмены новые загрузки не начинаются, а уже идущие успевают
+// завершиться и сохраниться в пределах ShutdownTimeout.
+func (p *Poller) Run(ctx context.Context, errCn chan<- error) error {
+	// Загрузки не зависят от ctx, чтобы при остановке дать им доработать
+	workCtx, stopWorkers := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopWorkers()
+
+	conf := p.config()
+	results := make(chan fetchResult)
+	ticker := time.NewTicker(conf.Tick)
+	defer ticker.Stop()
+
+	var queue []db.Feed
+	var pending []fetchResult
+	pendingNews := 0
+	// flush срабатывает через flushDelay после первой записи в pending
+	var flush <-chan time.Time
+	save := func() {
+		for _, err := range p.save(ctx, pending) {
+			errCn <- err
+		}
+		pending, pendingNews, flush = nil, 0, nil
+	}
+	inFlight := make(map[int]bool)
+	running := 0
+
+	for {
+		// Очередь разбирается, пока есть свободные места
+		for len(queue) > 0 && running < conf.Concurrency {
+			running++
+			go p.fetch(workCtx, queue[0], results)
+			queue = queue[1:]
+		}
+
+		select {
+		case <-ctx.Done():
+			p.drain(ctx, results, pending, running)
+			return ctx.Err()
+		case next := <-p.updates:
+			if next.Period < conf.Period {
+				// Ленты, назначенные по старому периоду, не должны ждать дольше нового
+				if err := p.store.ShortenSchedule(ctx, time.Now().Add(next.Period), int(next.Period/time.Second)); err != nil {
+					errCn <- err
+				}
+			}
+			if next.Tick != conf.Tick {
+				ticker.Reset(next.Tick)
+			}
+			next.ShutdownTimeout = conf.ShutdownTimeout
+			p.mu.Lock()
+			p.conf = next
+			p.mu.Unlock()
+			conf = next
+		case res := <-results:
+			running--
+			delete(inFlight, res.feed.ID)
+			if res.err != nil {
+				errCn <- res.err
+			} else {
+				pending = append(pending, res)
+				pendingNews += len(res.news)
+			}
+			switch {
+			case len(pending) == 0:
+			case pendingNews >= maxPending || running == 0:
+				save()
+			case flush == nil:
+				flush = time.After(flushDelay)
+			}
+		case <-flush:
+			save()
+		case <-ticker.C:
+			feeds, err := p.store.DueFeeds(ctx, time.Now())
+			if err != nil {
+				errCn <- err
+				continue
+			}
+			for _, feed := range feeds {
+				if inFlight[feed.ID] {
+					continue
+				}
+				inFlight[feed.ID] = true
+				queue = append(queue, feed)
+			}
+		}
+	}
+}
+
+// drain сохраняет накопленные записи и дожидается running запущенных
+// загрузок, сохраняя и их результаты. Ошибки только логируются: получатель
+// errCn к этому моменту сам останавливается.
+func (p *Poller) drain(ctx context.Context, results <-chan fetchResult, pending []fetchResult, running int) {
+	drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.config().ShutdownTimeout)
+	defer cancel()
+
+	for {
+		if len(pending) > 0 {
+			for _, err := range p.save(drainCtx, pending) {
+				fmt.Printf("failed to store news on shutdown: %v\n", err)
+			}
+			pending = nil
+		}
+		if running == 0 {
+			return
+		}
+
+		select {
+		case <-drainCtx.Done():
+			fmt.Printf("shutdown timeout: %d feed fetches abandoned\n", running)
+			return
+		case res := <-results:
+			running--
+			if res.err != nil {
+				fmt.Printf("failed to fetch %s on shutdown: %v\n", res.feed.URL, res.err)
+				continue
+			}
+			pending = []fetchResult{res}
+		}
+	}
+}
+
+// save сохраняет записи загрузок одной пачкой. Если пачка не сохранилась,
+// записи сохраняются по лентам, чтобы одна лента, например удалённая,
+// пока она загружалась, не лишила записей остальные. Записи удалённых
+// лент отбрасываются молча.
+func (p *Poller) save(ctx context.Context, batch []fetchResult) []error {
+	var news []db.News
+	for _, res := range batch {
+		news = append(news, res.news...)
+	}
+	batchErr := p.store.StoreNews(ctx, news)
+
+	var errs []error
+	for _, res := range batch {
+		err := batchErr
+		// Пачку из одной ленты сохранять повторно незачем
+		if err != nil && len(batch) > 1 {
+			err = p.store.StoreNews(ctx, res.news)
+		}
+		if err == nil {
+			// Записи сохранены, теперь можно запомнить ETag и Last-Modified:
+			// иначе следующий условный запрос получил бы 304 и записи пропали бы
+			err = p.store.UpdateFeedMeta(ctx, res.feed)
+		} else {
+			err = p.feedStoreError(ctx, res.feed, err)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// feedStoreError возвращает ошибку сохранения записей ленты или nil,
+// если ленты больше нет.
+func (p *Poller) feedStoreError(ctx context.Context, feed db.Feed, err error) error {
+	if _, ferr := p.store.Feed(ctx, feed.ID); errors.Is(ferr, db.ErrNotFound) {
+		return nil
+	}
+	return fmt.Errorf("failed to store news of %s: %w", feed.URL, err)
+}
+
+// fetch загружает одну ленту и отдаёт результат циклу Run.
+func (p *Poller) fetch(ctx context.Context, feed db.Feed, results chan<- fetchResult) {
+	feed, news, err := p.poll(ctx, feed)
+	select {
+	case results <- fetchResult{feed: feed, news: news, err: err}:
+	case <-ctx.Done():
+	}
+}
+
+// poll загружает ленту и назначает её следующий опрос. Возвращает ленту
+// с метаданными из ответа и записи. Ошибки загрузки не возвращаются,
+// а записываются в ленту и откладывают следующий опрос; наружу уходят
+// только ошибки базы.
+func (p *Poller) poll(ctx context.Context, src db.Feed) (db.Feed, []db.News, error) {
+	conf := p.config()
+	fetchCtx, cancel := context.WithTimeout(ctx, conf.FetchTimeout)
+	defer cancel()
+
+	prev := cacheInfo{etag: src.ETag, lastModified: src.LastModified, size: src.LastSize}
+	feed, info, err := load(fetchCtx, p.client, src.URL, prev)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Поллер останавливается, лента тут ни при чём
+			return src, nil, ctx.Err()
+		}
+		return src, nil, p.fail(ctx, conf, src, err)
+	}
+
+	var news []db.News
+	if feed == nil {
+		err = p.store.MarkNotModified(ctx, src.ID)
+	} else {
+		news, err = p.update(ctx, &src, feed, info)
+	}
+	if err != nil {
+		return src, nil, err
+	}
+
+	next := nextFetch(time.Now(), interval(src, conf.Period), src.SkipHours, src.SkipDays)
+	return src, news, p.store.ScheduleFeed(ctx, src.ID, next)
+}
+
+// fail записывает ошибку загрузки и откладывает следующий опрос с
+// экспоненциальной задержкой. После MaxFailures ошибок подряд лента отключается.
+func (p *Poller) fail(ctx context.Context, conf Config, src db.Feed, fetchErr error) error {
+	failures := src.ConsecutiveFailures + 1
+	delay := backoff(interval(src, conf.Period), failures)
+	var statusErr *StatusError
+	if errors.As(fetchErr, &statusErr) && statusErr.RetryAfter > delay {
+		delay = statusErr.RetryAfter
+	}
+
+	feed, err := p.store.FeedFailed(ctx, src.ID, fetchErr.Error(), time.Now().Add(delay), conf.MaxFailures)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			// Ленту удалили, пока она загружалась
+			return nil
+		}
+		return err
+	}
+	if !feed.Enabled {
+		fmt.Printf("feed %s disabled after %d consecutive failures: %v\n", src.URL, feed.ConsecutiveFailures, fetchErr)
+		return nil
+	}
+	fmt.Printf("failed to fetch %s (attempt %d, retry in %v): %v\n", src.URL, feed.ConsecutiveFailures,
+		delay.Round(time.Second), fetchErr)
+	return nil
+}
+
+// update сохраняет метаданные канала, включая подсказки о расписании,
+// и возвращает записи, готовые к сохранению. Новые валидаторы кэша
+// записываются в src, но в базу попадают только после записей.
+func (p *Poller) update(ctx context.Context, src *db.Feed, feed *parser.Feed, info cacheInfo) ([]db.News, error) {
+	for _, itemErr := range feed.Errors {
+		fmt.Printf("skipped item in %s: %v\n", src.URL, itemErr)
+	}
+
+	src.Title, src.SiteLink, src.Description, src.Icon = feed.Title, feed.Link, feed.Description, feed.Image
+	src.TTL = int(feed.TTL / time.Second)
+	src.SkipHours, src.SkipDays = hoursMask(feed.SkipHours), daysMask(feed.SkipDays)
+	if err := p.store.UpdateFeedMeta(ctx, *src); err != nil {
+		return nil, err
+	}
+	src.ETag, src.LastModified, src.LastSize = info.etag, info.lastModified, info.size
+
+	news := make([]db.News, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		description := item.Description
+		if description == "" {
+			// В Atom часто есть только <content>
+			description = item.Content
+		}
+		published := item.Published
+		if published.IsZero() {
+			if item.PubDate != "" {
+				fmt.Printf("unrecognized date %q in %s, using fetch time\n", item.PubDate, src.URL)
+			}
+			published = time.Now()
+		}
+		news = append(news, db.News{
+			FeedID:          src.ID,
+			Name:            item.Title,
+			Description:     parser.Text(description),
+			PublicationDate: published,
+			Link:            itemLink(item),
+			Author:          item.Author,
+			GUID:            itemGUID(item),
+			ContentHash:     db.ContentHash(item.Title, item.Description, item.Content),
+		})
+	}
+	return news, nil
+}
@@ -2,107 +2,223 @@ package rss
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"goNews/pkg/config"
 	"goNews/pkg/db"
+	"goNews/pkg/rss/parser"
 	"io"
 	"net/http"
-	"os"
-	"regexp"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
-type rss struct {
-	Links  []string `json:"rss"`
-	Period int      `json:"request_period"`
+// Rss регистрирует ленты из настроек и опрашивает все ленты до отмены контекста.
+// Настройки, пришедшие в reload, применяются на ходу: список лент сверяется
+// с базой, а поллер получает новые интервалы и число загрузок.
+func Rss(ctx context.Context, store db.Store, conf *config.Config, reload <-chan *config.Config, errCn chan<- error) error {
+	// Ленты из конфигурации регистрируются в таблице feeds, остальные
+	// добавляются через API и подхватываются на следующем тике
+	if err := syncFeeds(ctx, store, conf.Feeds); err != nil {
+		return err
+	}
+
+	poller := NewPoller(store, pollerConfig(conf))
+	// После перезапуска Rss настройки должен читать уже новый поллер
+	reloadCtx, stopReload := context.WithCancel(ctx)
+	defer stopReload()
+	go func() {
+		for {
+			select {
+			case <-reloadCtx.Done():
+				return
+			case conf := <-reload:
+				if err := syncFeeds(reloadCtx, store, conf.Feeds); err != nil {
+					errCn <- err
+				}
+				if err := poller.Reload(reloadCtx, pollerConfig(conf)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return poller.Run(ctx, errCn)
+}
+
+func pollerConfig(conf *config.Config) Config {
+	return Config{
+		Period:          time.Duration(conf.Period) * time.Second,
+		Concurrency:     conf.Concurrency,
+		FetchTimeout:    time.Duration(conf.FetchTimeout) * time.Second,
+		MaxFailures:     conf.MaxFailures,
+		ShutdownTimeout: time.Duration(conf.ShutdownTimeout) * time.Second,
+	}
 }
 
-func Rss(ctx context.Context, db *db.DB, errCn chan<- error) error {
-	file, err := os.ReadFile("./src/config.json")
+// syncFeeds сверяет ленты из настроек с базой и пишет в лог, что изменилось.
+func syncFeeds(ctx context.Context, store db.Store, feeds []string) error {
+	added, removed, err := store.SyncConfigFeeds(ctx, feeds)
 	if err != nil {
-		return fmt.Errorf("failed to read config.json: %w", err)
+		return err
+	}
+	for _, link := range added {
+		fmt.Printf("feed %s added from config\n", link)
+	}
+	for _, link := range removed {
+		fmt.Printf("feed %s removed from config, disabled\n", link)
 	}
+	return nil
+}
+
+// Probe однократно загружает адрес и проверяет, что по нему отдаётся лента.
+func Probe(ctx context.Context, link string) (*parser.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	feed, _, err := load(ctx, http.DefaultClient, link, cacheInfo{})
+	return feed, err
+}
 
-	var rssConf rss
-	if err := json.Unmarshal(file, &rssConf); err != nil {
-		return fmt.Errorf("failed to parse config.json: %w", err)
+const probeTimeout = 15 * time.Second
+
+// cacheInfo - валидаторы ответа для условных запросов и его размер.
+type cacheInfo struct {
+	etag         string
+	lastModified string
+	size         int64
+}
+
+// load скачивает и разбирает ленту. Время загрузки ограничивается контекстом.
+// Если переданы валидаторы прошлого ответа, запрос делается условным, и на
+// ответ 304 возвращается nil вместо ленты.
+func load(ctx context.Context, client *http.Client, link string, prev cacheInfo) (*parser.Feed, cacheInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("invalid feed url %s: %w", link, err)
 	}
+	if prev.etag != "" {
+		req.Header.Set("If-None-Match", prev.etag)
+	}
+	if prev.lastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.lastModified)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("HTTP request error for %s: %w", link, err)
+	}
+	defer resp.Body.Close()
 
-	if len(rssConf.Links) == 0 {
-		return fmt.Errorf("no RSS links provided in config")
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, prev, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, cacheInfo{}, &StatusError{
+			URL:        link,
+			Status:     resp.Status,
+			RetryAfter: retryAfter(resp, time.Now()),
+		}
 	}
 
-	if rssConf.Period <= 0 {
-		rssConf.Period = 60 // Устанавливаем значение по умолчанию, если не указано
+	body := &countingReader{r: resp.Body}
+	feed, err := parser.ParseWithType(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		if feed == nil {
+			return nil, cacheInfo{}, fmt.Errorf("error parsing feed %s: %w", link, err)
+		}
+		// Лента оборвалась на середине - сохраняем то, что успели разобрать
+		fmt.Printf("partially parsed feed %s: %v\n", link, err)
 	}
+	// Дочитываем хвост после корневого элемента, чтобы размер был полным
+	io.Copy(io.Discard, body)
 
-	ticker := time.NewTicker(time.Duration(rssConf.Period) * time.Second)
-	defer ticker.Stop()
+	info := cacheInfo{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		size:         body.n,
+	}
+	return feed, info, nil
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			var batchValues []interface{}
-			for _, link := range rssConf.Links {
-				resp, err := http.Get(link)
-				if err != nil {
-					errCn <- fmt.Errorf("HTTP request error for %s: %w", link, err)
-					continue
-				}
-				defer resp.Body.Close()
+// StatusError - ответ сервера с неожиданным кодом.
+type StatusError struct {
+	URL    string
+	Status string
+	// RetryAfter - через сколько сервер просит повторить запрос
+	// (Retry-After в ответах 429 и 503), ноль если не просит.
+	RetryAfter time.Duration
+}
 
-				body, err := io.ReadAll(resp.Body)
-				if err != nil {
-					errCn <- fmt.Errorf("error reading response from %s: %w", link, err)
-					continue
-				}
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %q for %s", e.Status, e.URL)
+}
 
-				reItem := regexp.MustCompile(`(?s)<item>.*?</item>`)
-				items := reItem.FindAllString(string(body), -1)
-
-				reTitle := regexp.MustCompile(`<title><!\[CDATA\[(.*?)]]></title>`)
-				rePubDate := regexp.MustCompile(`<pubDate>(.*?)</pubDate>`)
-				reDescription := regexp.MustCompile(`<description>(.*?)</description>`)
-				reCData := regexp.MustCompile(`<!\[CDATA\[(.*?)]]>`)
-				reTags := regexp.MustCompile(`(?s)<.*?>`)
-
-				for _, item := range items {
-					titleMatch := reTitle.FindStringSubmatch(item)
-					pubDateMatch := rePubDate.FindStringSubmatch(item)
-					descriptionMatch := reDescription.FindStringSubmatch(item)
-
-					if len(titleMatch) > 1 && len(pubDateMatch) > 1 && len(descriptionMatch) > 1 {
-						title := titleMatch[1]
-						pubDate := pubDateMatch[1]
-						description := descriptionMatch[1]
-
-						cdataMatch := reCData.FindStringSubmatch(description)
-						if len(cdataMatch) > 1 {
-							description = cdataMatch[1]
-						}
-						description = reTags.ReplaceAllString(description, "")
-
-						batchValues = append(batchValues, title, description, pubDate, link)
-					}
-				}
-			}
+// retryAfter разбирает Retry-After в виде числа секунд или HTTP-даты.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
 
-			if len(batchValues) > 0 {
-				query := "INSERT INTO news (name, description, publication_date, link) VALUES "
-				placeholders := make([]string, 0, len(batchValues)/4)
-				for i := 0; i < len(batchValues); i += 4 {
-					placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d)", i+1, i+2, i+3, i+4))
-				}
-				query += fmt.Sprintf("%s ON CONFLICT (name) DO NOTHING;", strings.Join(placeholders, ","))
+// countingReader считает прочитанные байты.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
 
-				_, err = db.Pool.Exec(ctx, query, batchValues...)
-				if err != nil {
-					errCn <- fmt.Errorf("batch insert error: %w", err)
-				}
-			}
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// itemLink возвращает ссылку на статью. Если <link> нет, её роль
+// может играть guid, когда он является адресом.
+func itemLink(item parser.Item) string {
+	if item.Link != "" {
+		return item.Link
+	}
+	if strings.HasPrefix(item.GUID, "http://") || strings.HasPrefix(item.GUID, "https://") {
+		return item.GUID
+	}
+	return ""
+}
+
+// itemGUID выбирает идентификатор записи: guid/id из ленты, затем
+// канонизированную ссылку и только в крайнем случае заголовок.
+func itemGUID(item parser.Item) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	if link := canonicalLink(item.Link); link != "" {
+		return link
+	}
+	return item.Title
+}
+
+// canonicalLink убирает из ссылки фрагмент и utm-метки, которые у одной
+// и той же статьи меняются от выпуска к выпуску.
+func canonicalLink(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(link)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(key, "utm_") {
+			query.Del(key)
 		}
 	}
+	u.RawQuery = query.Encode()
+	return u.String()
 }
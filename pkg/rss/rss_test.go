@@ -2,60 +2,25 @@ package rss
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v4/pgxpool"
+	"goNews/pkg/config"
 	"goNews/pkg/db"
+	"goNews/pkg/db/memory"
+	"goNews/pkg/rss/parser"
 )
 
-// TestMain подготавливает тестовую базу данных
-func TestMain(m *testing.M) {
-	ctx := context.Background()
-	pwd := os.Getenv("dbpass")
-	connStr := "postgres://postgres:" + pwd + "@localhost:5432/GoNews?sslmode=disable"
-
-	// Подключаемся к базе данных
-	pool, err := pgxpool.Connect(ctx, connStr)
-	if err != nil {
-		fmt.Printf("Failed to connect to database: %v\n", err)
-		os.Exit(1)
-	}
-	defer pool.Close()
-
-	// Очищаем таблицу news перед тестами
-	_, err = pool.Exec(ctx, "TRUNCATE TABLE news RESTART IDENTITY;")
-	if err != nil {
-		fmt.Printf("Failed to truncate news table: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Запускаем тесты
-	code := m.Run()
-
-	// Очищаем таблицу после тестов
-	_, err = pool.Exec(ctx, "TRUNCATE TABLE news RESTART IDENTITY;")
-	if err != nil {
-		fmt.Printf("Failed to clean up news table: %v\n", err)
-	}
-
-	os.Exit(code)
-}
-
-// setupTestDB создает тестовую базу данных
-func setupTestDB(t *testing.T) *db.DB {
-	ctx := context.Background()
-	errChan := make(chan error, 1)
-	dbInstance := db.New(ctx, errChan)
-	if dbInstance == nil {
-		t.Fatalf("Failed to initialize database: %v", <-errChan)
-	}
-	return dbInstance
+// setupTestDB возвращает пустое хранилище в памяти
+func setupTestDB(t *testing.T) db.Store {
+	return memory.New()
 }
 
 // TestRss проверяет функцию Rss
@@ -100,22 +65,13 @@ func TestRss(t *testing.T) {
 		"rss": ["%s"],
 		"request_period": 1
 	}`, srv.URL)
-	configPath := filepath.Join(tempDir, "src")
-	if err := os.MkdirAll(configPath, 0755); err != nil {
-		t.Fatalf("Failed to create src dir: %v", err)
-	}
-	if err := os.WriteFile(filepath.Join(configPath, "config.json"), []byte(configContent), 0644); err != nil {
+	configPath := filepath.Join(tempDir, "config.json")
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 		t.Fatalf("Failed to write config.json: %v", err)
 	}
-
-	// Переключаем рабочую директорию
-	origDir, err := os.Getwd()
+	conf, err := config.Load([]string{"-config", configPath})
 	if err != nil {
-		t.Fatalf("Failed to get current dir: %v", err)
-	}
-	defer os.Chdir(origDir)
-	if err := os.Chdir(tempDir); err != nil {
-		t.Fatalf("Failed to change dir: %v", err)
+		t.Fatalf("Failed to load config: %v", err)
 	}
 
 	// Подготавливаем базу данных
@@ -128,7 +84,7 @@ func TestRss(t *testing.T) {
 	errChan := make(chan error, 1)
 
 	go func() {
-		if err := Rss(ctx, dbInstance, errChan); err != nil {
+		if err := Rss(ctx, dbInstance, conf, nil, errChan); err != nil {
 			errChan <- err
 		}
 	}()
@@ -137,34 +93,24 @@ func TestRss(t *testing.T) {
 	select {
 	case <-ctx.Done():
 		// Проверяем, что данные были вставлены
-		rows, err := dbInstance.Pool.Query(ctx, "SELECT name, description, publication_date, link FROM news")
+		page, err := dbInstance.News(context.Background(), db.NewsQuery{Limit: 100})
+		newsItems := page.News
 		if err != nil {
 			t.Fatalf("Failed to query news: %v", err)
 		}
-		defer rows.Close()
-
-		newsItems := make([]db.News, 0)
-		for rows.Next() {
-			var news db.News
-			if err := rows.Scan(&news.Name, &news.Description, &news.PublicationDate, &news.Link); err != nil {
-				t.Errorf("Failed to scan news: %v", err)
-				continue
-			}
-			newsItems = append(newsItems, news)
-		}
 
 		if len(newsItems) < 2 {
 			t.Errorf("Expected at least 2 news items, got %d", len(newsItems))
 		}
 
 		expected := []db.News{
-			{Name: "Test News 1", Description: "Description 1", PublicationDate: "Mon, 01 Jan 2023 00:00:00 GMT", Link: "http://example.com/1"},
-			{Name: "Test News 2", Description: "Description 2 with tags", PublicationDate: "Tue, 02 Jan 2023 00:00:00 GMT", Link: "http://example.com/2"},
+			{Name: "Test News 1", Description: "Description 1", PublicationDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Link: "http://example.com/1"},
+			{Name: "Test News 2", Description: "Description 2 with tags", PublicationDate: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), Link: "http://example.com/2"},
 		}
 		for _, exp := range expected {
 			found := false
 			for _, got := range newsItems {
-				if got.Name == exp.Name && got.Description == exp.Description && got.PublicationDate == exp.PublicationDate && got.Link == exp.Link {
+				if got.Name == exp.Name && got.Description == exp.Description && got.PublicationDate.Equal(exp.PublicationDate) && got.Link == exp.Link {
 					found = true
 					break
 				}
@@ -177,3 +123,678 @@ func TestRss(t *testing.T) {
 		t.Fatalf("Rss failed unexpectedly: %v", err)
 	}
 }
+
+// TestParseFixtures прогоняет разбор по корпусу реальных лент из testdata
+func TestParseFixtures(t *testing.T) {
+	tests := []struct {
+		file        string
+		feedTitle   string
+		image       string
+		items       int
+		itemErrors  int
+		firstTitle  string
+		firstAuthor string
+	}{
+		{
+			file:        "habr.xml",
+			feedTitle:   "Go – Язык программирования / Хабр",
+			image:       "https://habr.com/img/habr_ru.png",
+			items:       3,
+			firstTitle:  "Как мы переписали сервис уведомлений на Go",
+			firstAuthor: "gopher_dev",
+		},
+		{
+			file:       "golangweekly.xml",
+			feedTitle:  "Golang Weekly",
+			items:      2,
+			firstTitle: "Go 1.23.2 released, plus iterators in practice",
+		},
+		{
+			file:        "attributes.xml",
+			feedTitle:   "Example Engineering Blog",
+			items:       2,
+			itemErrors:  1,
+			firstTitle:  "Shipping\u00a0faster with smaller PRs",
+			firstAuthor: "Jane Roe",
+		},
+		{
+			file:        "atom.xml",
+			feedTitle:   "The Go Blog",
+			items:       2,
+			firstTitle:  "Go 1.23 is released",
+			firstAuthor: "Dmitri Shuralyov, on behalf of the Go team",
+		},
+		{
+			file:        "jsonfeed.json",
+			feedTitle:   "Go Newsletter",
+			items:       2,
+			itemErrors:  1,
+			firstTitle:  "Issue #101: structured logging with slog",
+			firstAuthor: "Alex Doe",
+		},
+		{
+			// Документ начинается с BOM UTF-8
+			file:        "jsonfeed_bom.json",
+			feedTitle:   "Заметки о Go",
+			items:       1,
+			firstTitle:  "Дженерики через год",
+			firstAuthor: "Иван Петров",
+		},
+		{
+			file:        "rdf.xml",
+			feedTitle:   "LWN.net headlines",
+			items:       2,
+			firstTitle:  "A new garbage collector for Go",
+			firstAuthor: "corbet",
+		},
+		{
+			file:       "cp1251.xml",
+			feedTitle:  "Новости Go",
+			items:      1,
+			firstTitle: "Вышел Go 1.23",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("Failed to open fixture: %v", err)
+			}
+			defer f.Close()
+
+			feed, err := parser.Parse(f)
+			if err != nil {
+				t.Fatalf("Failed to parse fixture: %v", err)
+			}
+			if feed.Title != tt.feedTitle {
+				t.Errorf("Expected feed title %q, got %q", tt.feedTitle, feed.Title)
+			}
+			if feed.Image != tt.image {
+				t.Errorf("Expected feed image %q, got %q", tt.image, feed.Image)
+			}
+			if len(feed.Items) != tt.items {
+				t.Fatalf("Expected %d items, got %d", tt.items, len(feed.Items))
+			}
+			if len(feed.Errors) != tt.itemErrors {
+				t.Errorf("Expected %d item errors, got %v", tt.itemErrors, feed.Errors)
+			}
+			if feed.Items[0].Title != tt.firstTitle {
+				t.Errorf("Expected first title %q, got %q", tt.firstTitle, feed.Items[0].Title)
+			}
+			if feed.Items[0].Author != tt.firstAuthor {
+				t.Errorf("Expected first author %q, got %q", tt.firstAuthor, feed.Items[0].Author)
+			}
+		})
+	}
+}
+
+// TestItemGUID проверяет выбор идентификатора записи
+func TestItemGUID(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     parser.Item
+		expected string
+	}{
+		{
+			name:     "GUID from feed",
+			item:     parser.Item{GUID: "tag:example.com,2024:1", Link: "http://example.com/1", Title: "Title"},
+			expected: "tag:example.com,2024:1",
+		},
+		{
+			name:     "Canonical link fallback",
+			item:     parser.Item{Link: "HTTPS://Example.com/post?id=7&utm_source=rss&utm_medium=feed#comments", Title: "Title"},
+			expected: "https://example.com/post?id=7",
+		},
+		{
+			name:     "Title as last resort",
+			item:     parser.Item{Title: "Title"},
+			expected: "Title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemGUID(tt.item); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestPollerConcurrency проверяет, что медленная лента не загружается
+// повторно, пока предыдущая загрузка не закончилась, а число одновременных
+// загрузок не превышает заданного.
+func TestPollerConcurrency(t *testing.T) {
+	const feedXML = `<rss version="2.0"><channel><title>Slow</title>
+		<item><title>Item</title><guid>%s</guid><description>d</description></item>
+	</channel></rss>`
+
+	var mu sync.Mutex
+	active := make(map[string]int)
+	total, maxTotal, maxPerFeed, requests := 0, 0, 0, 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active[r.URL.Path]++
+		total++
+		requests++
+		if active[r.URL.Path] > maxPerFeed {
+			maxPerFeed = active[r.URL.Path]
+		}
+		if total > maxTotal {
+			maxTotal = total
+		}
+		mu.Unlock()
+
+		// Загрузка длится дольше периода опроса
+		time.Sleep(150 * time.Millisecond)
+		fmt.Fprintf(w, feedXML, r.URL.Path)
+
+		mu.Lock()
+		active[r.URL.Path]--
+		total--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	store := setupTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if _, err := store.AddFeed(ctx, fmt.Sprintf("%s/feed%d", srv.URL, i)); err != nil {
+			t.Fatalf("AddFeed() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	errChan := make(chan error, 100)
+	poller := NewPoller(store, Config{Period: 20 * time.Millisecond, Tick: 10 * time.Millisecond, Concurrency: 2,
+		FetchTimeout: time.Second})
+	poller.Run(ctx, errChan)
+
+	select {
+	case err := <-errChan:
+		t.Fatalf("poller error: %v", err)
+	default:
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests == 0 {
+		t.Fatal("no feeds were fetched")
+	}
+	if maxPerFeed != 1 {
+		t.Errorf("feed fetched concurrently: max %d parallel requests", maxPerFeed)
+	}
+	if maxTotal > 2 {
+		t.Errorf("concurrency limit exceeded: %d parallel requests, want <= 2", maxTotal)
+	}
+}
+
+// TestPollerReload проверяет, что новое число загрузок применяется на ходу
+func TestPollerReload(t *testing.T) {
+	var mu sync.Mutex
+	total, maxTotal := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		total++
+		if total > maxTotal {
+			maxTotal = total
+		}
+		mu.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Feed</title></channel></rss>`)
+
+		mu.Lock()
+		total--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	store := setupTestDB(t)
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if _, err := store.AddFeed(ctx, fmt.Sprintf("%s/feed%d", srv.URL, i)); err != nil {
+			t.Fatalf("AddFeed() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	conf := Config{Period: 20 * time.Millisecond, Tick: 10 * time.Millisecond, Concurrency: 1, FetchTimeout: time.Second}
+	poller := NewPoller(store, conf)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		poller.Run(ctx, make(chan error, 100))
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	mu.Lock()
+	before := maxTotal
+	mu.Unlock()
+	if before != 1 {
+		t.Errorf("Expected 1 parallel request before reload, got %d", before)
+	}
+
+	conf.Concurrency = 4
+	if err := poller.Reload(ctx, conf); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxTotal <= 1 || maxTotal > 4 {
+		t.Errorf("Expected 2-4 parallel requests after reload, got %d", maxTotal)
+	}
+}
+
+// faultyStore - хранилище в памяти, методы которого можно заставить
+// возвращать ошибку
+type faultyStore struct {
+	*memory.Store
+	mu           sync.Mutex
+	failStore    bool
+	failSchedule map[int]bool
+}
+
+func (s *faultyStore) StoreNews(ctx context.Context, news []db.News) error {
+	s.mu.Lock()
+	fail := s.failStore
+	s.mu.Unlock()
+	if fail {
+		return errors.New("store failed")
+	}
+	return s.Store.StoreNews(ctx, news)
+}
+
+func (s *faultyStore) ScheduleFeed(ctx context.Context, id int, next time.Time) error {
+	s.mu.Lock()
+	fail := s.failSchedule[id]
+	s.mu.Unlock()
+	if fail {
+		return errors.New("schedule failed")
+	}
+	return s.Store.ScheduleFeed(ctx, id, next)
+}
+
+// waitNews ждёт, пока в хранилище не окажется count записей
+func waitNews(t *testing.T, store db.Store, count int) []db.News {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		page, err := store.News(context.Background(), db.NewsQuery{Limit: 100})
+		if err != nil {
+			t.Fatalf("News() error = %v", err)
+		}
+		if len(page.News) >= count || time.Now().After(deadline) {
+			return page.News
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPollerSave проверяет, что записи сохраняются, даже когда соседняя
+// загрузка в пачке завершилась ошибкой или её лента удалена
+func TestPollerSave(t *testing.T) {
+	const feedXML = `<rss version="2.0"><channel><title>Feed</title>
+		<item><title>Item</title><guid>%s</guid><description>d</description></item>
+	</channel></rss>`
+
+	tests := []struct {
+		name string
+		// broken - что случается со второй лентой, пока она загружается
+		broken         func(store *faultyStore, id int)
+		expectedErrors int
+	}{
+		{
+			name: "Feed deleted during fetch",
+			broken: func(store *faultyStore, id int) {
+				store.DeleteFeed(context.Background(), id)
+			},
+		},
+		{
+			name: "Last fetch fails",
+			broken: func(store *faultyStore, id int) {
+				store.mu.Lock()
+				store.failSchedule[id] = true
+				store.mu.Unlock()
+			},
+			expectedErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &faultyStore{Store: memory.New(), failSchedule: make(map[int]bool)}
+			ctx := context.Background()
+			var brokenID int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/good" {
+					// Исправная лента отвечает первой, сломанная - последней
+					fmt.Fprintf(w, feedXML, r.URL.Path)
+					return
+				}
+				time.Sleep(100 * time.Millisecond)
+				tt.broken(store, brokenID)
+				fmt.Fprintf(w, feedXML, r.URL.Path)
+			}))
+			defer srv.Close()
+
+			if _, err := store.AddFeed(ctx, srv.URL+"/good"); err != nil {
+				t.Fatalf("AddFeed() error = %v", err)
+			}
+			broken, err := store.AddFeed(ctx, srv.URL+"/broken")
+			if err != nil {
+				t.Fatalf("AddFeed() error = %v", err)
+			}
+			brokenID = broken.ID
+
+			ctx, cancel := context.WithCancel(ctx)
+			errChan := make(chan error, 10)
+			done := make(chan struct{})
+			poller := NewPoller(store, Config{Period: time.Hour, Tick: 10 * time.Millisecond, FetchTimeout: time.Second})
+			go func() {
+				defer close(done)
+				poller.Run(ctx, errChan)
+			}()
+
+			// Записи сохраняются, пока поллер ещё работает
+			news := waitNews(t, store, 1)
+			cancel()
+			<-done
+			if len(news) != 1 || news[0].GUID != "/good" {
+				t.Errorf("Expected the good feed's news to be stored, got %+v", news)
+			}
+			if len(errChan) != tt.expectedErrors {
+				t.Errorf("Expected %d errors, got %d", tt.expectedErrors, len(errChan))
+			}
+		})
+	}
+}
+
+// TestPollerStoreFailure проверяет, что после неудачного сохранения записей
+// лента загружается снова целиком, а не получает 304
+func TestPollerStoreFailure(t *testing.T) {
+	var mu sync.Mutex
+	var requests, conditional int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+		}
+		mu.Unlock()
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Feed</title>
+			<item><title>Item</title><guid>1</guid><description>d</description></item>
+		</channel></rss>`)
+	}))
+	defer srv.Close()
+
+	store := &faultyStore{Store: memory.New(), failStore: true, failSchedule: make(map[int]bool)}
+	ctx := context.Background()
+	feed, err := store.AddFeed(ctx, srv.URL)
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+
+	run := func() []error {
+		ctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+		defer cancel()
+		errChan := make(chan error, 10)
+		NewPoller(store, Config{Period: time.Hour, Tick: 10 * time.Millisecond, FetchTimeout: time.Second}).Run(ctx, errChan)
+		close(errChan)
+		var errs []error
+		for err := range errChan {
+			errs = append(errs, err)
+		}
+		return errs
+	}
+
+	if errs := run(); len(errs) == 0 {
+		t.Fatal("Expected a store error")
+	}
+	if f, _ := store.Feed(ctx, feed.ID); f.ETag != "" {
+		t.Errorf("ETag %q saved without the news", f.ETag)
+	}
+
+	store.mu.Lock()
+	store.failStore = false
+	store.mu.Unlock()
+	if err := store.ScheduleFeed(ctx, feed.ID, time.Now()); err != nil {
+		t.Fatalf("ScheduleFeed() error = %v", err)
+	}
+	if errs := run(); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if news := waitNews(t, store, 1); len(news) != 1 {
+		t.Errorf("Expected the news to be stored on the next poll, got %+v", news)
+	}
+	if f, _ := store.Feed(ctx, feed.ID); f.ETag != `"v1"` {
+		t.Errorf("Expected ETag to be saved with the news, got %q", f.ETag)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 2 || conditional != 0 {
+		t.Errorf("Expected 2 unconditional requests, got %d requests, %d conditional", requests, conditional)
+	}
+}
+
+// TestNextFetch проверяет выбор интервала опроса и обход skipHours/skipDays
+func TestNextFetch(t *testing.T) {
+	// Понедельник, 10:30 UTC
+	from := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		feed     db.Feed
+		expected time.Time
+	}{
+		{
+			name:     "default period",
+			feed:     db.Feed{},
+			expected: from.Add(time.Minute),
+		},
+		{
+			name:     "channel ttl",
+			feed:     db.Feed{TTL: 3600},
+			expected: from.Add(time.Hour),
+		},
+		{
+			name:     "ttl below default period",
+			feed:     db.Feed{TTL: 10},
+			expected: from.Add(time.Minute),
+		},
+		{
+			name:     "user interval wins",
+			feed:     db.Feed{TTL: 3600, FetchInterval: 30},
+			expected: from.Add(30 * time.Second),
+		},
+		{
+			name:     "skip hours",
+			feed:     db.Feed{TTL: 3600, SkipHours: hoursMask([]int{11, 12})},
+			expected: time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skip days",
+			feed:     db.Feed{TTL: 24 * 3600, SkipDays: daysMask([]time.Weekday{time.Tuesday, time.Wednesday})},
+			expected: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "everything skipped",
+			feed:     db.Feed{SkipDays: 1<<7 - 1},
+			expected: from.Add(time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextFetch(from, interval(tt.feed, time.Minute), tt.feed.SkipHours, tt.feed.SkipDays)
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestLoadConditional проверяет условные запросы с ETag и Last-Modified
+func TestLoadConditional(t *testing.T) {
+	const body = `<rss version="2.0"><channel><title>Cached</title>
+		<item><title>Item</title><description>d</description></item>
+	</channel></rss>`
+	const lastModified = "Mon, 01 Jan 2024 00:00:00 GMT"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		prev        cacheInfo
+		notModified bool
+	}{
+		{name: "first fetch", prev: cacheInfo{}},
+		{name: "etag", prev: cacheInfo{etag: `"v1"`, size: 10}, notModified: true},
+		{name: "last modified", prev: cacheInfo{lastModified: lastModified, size: 10}, notModified: true},
+		{name: "stale etag", prev: cacheInfo{etag: `"v0"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, info, err := load(context.Background(), srv.Client(), srv.URL, tt.prev)
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			if tt.notModified {
+				if feed != nil {
+					t.Errorf("Expected no feed on 304, got %+v", feed)
+				}
+				if info != tt.prev {
+					t.Errorf("Expected cache info %+v to be kept, got %+v", tt.prev, info)
+				}
+				return
+			}
+			if feed == nil || feed.Title != "Cached" {
+				t.Fatalf("Expected parsed feed, got %+v", feed)
+			}
+			exp := cacheInfo{etag: `"v1"`, lastModified: lastModified, size: int64(len(body))}
+			if info != exp {
+				t.Errorf("Expected cache info %+v, got %+v", exp, info)
+			}
+		})
+	}
+}
+
+// TestBackoff проверяет рост задержки после ошибок и её ограничение
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		every    time.Duration
+		failures int
+		max      time.Duration
+	}{
+		{name: "first failure", every: time.Minute, failures: 1, max: time.Minute},
+		{name: "doubles", every: time.Minute, failures: 4, max: 8 * time.Minute},
+		{name: "capped", every: time.Minute, failures: 50, max: maxBackoff},
+		{name: "long interval is not shortened", every: 24 * time.Hour, failures: 5, max: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := backoff(tt.every, tt.failures)
+				if got < tt.max/2 || got > tt.max {
+					t.Fatalf("Expected delay in [%v, %v], got %v", tt.max/2, tt.max, got)
+				}
+			}
+		})
+	}
+}
+
+// TestRetryAfter проверяет разбор заголовка Retry-After
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		status   int
+		header   string
+		expected time.Duration
+	}{
+		{name: "seconds", status: http.StatusTooManyRequests, header: "120", expected: 2 * time.Minute},
+		{name: "http date", status: http.StatusServiceUnavailable, header: "Mon, 01 Jan 2024 12:30:00 GMT", expected: 30 * time.Minute},
+		{name: "date in the past", status: http.StatusServiceUnavailable, header: "Mon, 01 Jan 2024 11:00:00 GMT"},
+		{name: "garbage", status: http.StatusTooManyRequests, header: "soon"},
+		{name: "ignored for other statuses", status: http.StatusNotFound, header: "120"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{"Retry-After": []string{tt.header}}}
+			if got := retryAfter(resp, now); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestPollerSlowFeed проверяет, что медленный сервер не задерживает
+// сохранение записей лент, загрузка которых уже закончилась
+func TestPollerSlowFeed(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Slow</title>
+			<item><title>Slow item</title><guid>slow</guid></item>
+		</channel></rss>`)
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Fast</title>
+			<item><title>Fast item</title><guid>fast</guid></item>
+		</channel></rss>`)
+	}))
+	defer fast.Close()
+
+	store := memory.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := store.AddFeed(ctx, slow.URL); err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	feed, err := store.AddFeed(ctx, fast.URL)
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+
+	errChan := make(chan error, 10)
+	go NewPoller(store, Config{Period: time.Hour, Tick: 10 * time.Millisecond, FetchTimeout: time.Minute}).Run(ctx, errChan)
+
+	news := waitNews(t, store, 1)
+	if len(news) != 1 || news[0].GUID != "fast" {
+		t.Fatalf("Expected the fast feed news while the slow one loads, got %+v", news)
+	}
+	if f, _ := store.Feed(ctx, feed.ID); f.ETag != `"v1"` {
+		t.Errorf("Expected ETag of the fast feed to be saved, got %q", f.ETag)
+	}
+}
@@ -0,0 +1,78 @@
+package rss
+
+import (
+	"goNews/pkg/db"
+	"math/rand"
+	"time"
+)
+
+// interval выбирает период опроса ленты: заданный пользователем, иначе
+// объявленный каналом, но не чаще периода по умолчанию.
+func interval(feed db.Feed, period time.Duration) time.Duration {
+	if feed.FetchInterval > 0 {
+		return time.Duration(feed.FetchInterval) * time.Second
+	}
+	if ttl := time.Duration(feed.TTL) * time.Second; ttl > period {
+		return ttl
+	}
+	return period
+}
+
+// nextFetch возвращает время следующего опроса, сдвигая его за пределы
+// часов и дней, которые канал перечислил в skipHours и skipDays.
+func nextFetch(from time.Time, every time.Duration, skipHours, skipDays int) time.Time {
+	next := from.Add(every).UTC()
+	// За неделю перебираются все сочетания часа и дня; если пропущено всё,
+	// подсказки канала игнорируются.
+	for i := 0; i < 7*24; i++ {
+		if skipDays&(1<<uint(next.Weekday())) != 0 {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if skipHours&(1<<uint(next.Hour())) != 0 {
+			next = next.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		return next
+	}
+	return from.Add(every)
+}
+
+// hoursMask и daysMask переводят списки из ленты в битовые маски для базы.
+func hoursMask(hours []int) int {
+	mask := 0
+	for _, hour := range hours {
+		mask |= 1 << uint(hour)
+	}
+	return mask
+}
+
+func daysMask(days []time.Weekday) int {
+	mask := 0
+	for _, day := range days {
+		mask |= 1 << uint(day)
+	}
+	return mask
+}
+
+// maxBackoff ограничивает задержку после ошибок, если обычный интервал
+// ленты не больше.
+const maxBackoff = 12 * time.Hour
+
+// backoff возвращает задержку перед повторной попыткой после failures ошибок
+// подряд: обычный интервал удваивается с каждой ошибкой, а случайный разброс
+// не даёт лентам одного хоста повторяться одновременно.
+func backoff(every time.Duration, failures int) time.Duration {
+	limit := maxBackoff
+	if every > limit {
+		limit = every
+	}
+	delay := every
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
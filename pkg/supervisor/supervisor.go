@@ -0,0 +1,125 @@
+// Package supervisor запускает подсистемы сервера и перезапускает их после сбоев.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Задержки между перезапусками подсистемы.
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// resetAfter - если подсистема проработала дольше, задержка
+	// перезапуска снова начинается с minBackoff.
+	resetAfter = 5 * time.Minute
+)
+
+var errStopped = errors.New("stopped unexpectedly")
+
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fatalError) Unwrap() error {
+	return e.err
+}
+
+// Fatal помечает ошибку как неустранимую: перезапуск подсистемы её не
+// исправит, и процесс должен завершиться. Остальные ошибки считаются
+// временными.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fatalError{err: err}
+}
+
+// IsFatal сообщает, помечена ли ошибка или одна из обёрнутых в неё как неустранимая.
+func IsFatal(err error) bool {
+	var fatal *fatalError
+	return errors.As(err, &fatal)
+}
+
+// Service - подсистема под надзором. Run должен работать до отмены
+// контекста, более ранний возврат считается сбоем.
+type Service struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Supervisor перезапускает упавшие подсистемы с растущей задержкой.
+// Неустранимые ошибки отправляются в errCn, решение о выходе остаётся за main.
+type Supervisor struct {
+	errCn      chan<- error
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func New(errCn chan<- error) *Supervisor {
+	return &Supervisor{errCn: errCn, minBackoff: minBackoff, maxBackoff: maxBackoff}
+}
+
+// Run запускает подсистемы и возвращается, когда все они остановились:
+// после отмены контекста или неустранимой ошибки.
+func (s *Supervisor) Run(ctx context.Context, services ...Service) {
+	var wg sync.WaitGroup
+	for _, svc := range services {
+		wg.Add(1)
+		go func(svc Service) {
+			defer wg.Done()
+			s.supervise(ctx, svc)
+		}(svc)
+	}
+	wg.Wait()
+}
+
+func (s *Supervisor) supervise(ctx context.Context, svc Service) {
+	delay := s.minBackoff
+	for {
+		started := time.Now()
+		err := run(ctx, svc)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errStopped
+		}
+		err = fmt.Errorf("%s: %w", svc.Name, err)
+		if IsFatal(err) {
+			s.errCn <- err
+			return
+		}
+
+		if time.Since(started) > resetAfter {
+			delay = s.minBackoff
+		}
+		fmt.Printf("%v, restarting in %v\n", err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > s.maxBackoff {
+			delay = s.maxBackoff
+		}
+	}
+}
+
+// run запускает подсистему, превращая панику в обычную ошибку.
+func run(ctx context.Context, svc Service) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return svc.Run(ctx)
+}
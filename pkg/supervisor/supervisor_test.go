@@ -0,0 +1,108 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestIsFatal проверяет классификацию ошибок
+func TestIsFatal(t *testing.T) {
+	base := errors.New("boom")
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "plain", err: base, expected: false},
+		{name: "fatal", err: Fatal(base), expected: true},
+		{name: "wrapped fatal", err: fmt.Errorf("poller: %w", Fatal(base)), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFatal(tt.err); got != tt.expected {
+				t.Errorf("IsFatal(%v) = %v, want %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+	if !errors.Is(Fatal(base), base) {
+		t.Error("Fatal must keep the original error")
+	}
+	if Fatal(nil) != nil {
+		t.Error("Fatal(nil) must be nil")
+	}
+}
+
+// TestSupervisor проверяет перезапуск после временных ошибок и паник
+// и остановку на неустранимой ошибке
+func TestSupervisor(t *testing.T) {
+	tests := []struct {
+		name  string
+		fail  func(attempt int) error
+		fatal bool
+		runs  int
+	}{
+		{
+			name: "restarts after errors and panics",
+			fail: func(attempt int) error {
+				if attempt == 2 {
+					panic("unexpected")
+				}
+				return errors.New("temporary")
+			},
+			runs: 3,
+		},
+		{
+			name: "restarts after unexpected return",
+			fail: func(attempt int) error { return nil },
+			runs: 3,
+		},
+		{
+			name:  "stops on fatal error",
+			fail:  func(attempt int) error { return Fatal(errors.New("bad config")) },
+			fatal: true,
+			runs:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			errChan := make(chan error, 1)
+			s := New(errChan)
+			s.minBackoff, s.maxBackoff = time.Millisecond, time.Millisecond
+
+			runs := 0
+			s.Run(ctx, Service{Name: "test", Run: func(ctx context.Context) error {
+				runs++
+				if runs > tt.runs-1 && !tt.fatal {
+					// Последний запуск работает до отмены контекста
+					cancel()
+					<-ctx.Done()
+					return nil
+				}
+				return tt.fail(runs)
+			}})
+
+			if runs != tt.runs {
+				t.Errorf("Expected %d runs, got %d", tt.runs, runs)
+			}
+			select {
+			case err := <-errChan:
+				if !tt.fatal || !IsFatal(err) {
+					t.Errorf("Unexpected error reported: %v", err)
+				}
+			default:
+				if tt.fatal {
+					t.Error("Expected fatal error to be reported")
+				}
+			}
+		})
+	}
+}
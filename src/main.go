@@ -2,78 +2,190 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"goNews/pkg/api"
+	"goNews/pkg/config"
 	"goNews/pkg/db"
+	"goNews/pkg/db/sqlite"
 	"goNews/pkg/rss"
-	"net/http"
+	"goNews/pkg/supervisor"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
+// watchInterval - как часто проверяется, не изменился ли файл настроек.
+const watchInterval = 5 * time.Second
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		case "opml":
+			os.Exit(runOPML(os.Args[2:]))
+		}
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	if err := serve(os.Args[1:], sigChan); err != nil {
+		fmt.Printf("Fatal error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve запускает поллер и HTTP-сервер с настройками из args и работает до
+// сигнала из stop или неустранимой ошибки, которую и возвращает.
+func serve(args []string, stop <-chan os.Signal) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Load проверяет настройки через Validate. С ошибочными настройками
+	// запускать нечего; при перезагрузке та же ошибка оставляет прежние
+	conf, err := config.Load(args)
+	if err != nil {
+		return supervisor.Fatal(fmt.Errorf("failed to load config: %w", err))
+	}
+	if len(conf.Args) > 0 {
+		return supervisor.Fatal(fmt.Errorf("unknown command %q, subcommands are migrate and opml", conf.Args[0]))
+	}
+	shutdown := time.Duration(conf.ShutdownTimeout) * time.Second
+
 	errChan := make(chan error, 10)
 
 	// Инициализация базы данных
-	dbInstance := db.New(ctx, errChan)
-	if dbInstance == nil {
-		fmt.Println("Failed to initialize database, exiting...")
-		select {
-		case err := <-errChan:
-			fmt.Printf("Error: %v\n", err)
-		default:
-			fmt.Println("Unknown database initialization error")
-		}
-		return
+	dbInstance, err := openStore(ctx, conf.Database, errChan)
+	if err != nil {
+		// Без базы работать нечему: повторы уже сделаны при подключении
+		return supervisor.Fatal(fmt.Errorf("failed to initialize database: %w", err))
 	}
+	// Пул закрывается последним, когда поллер и сервер уже остановлены
 	defer dbInstance.Close()
 
 	// Инициализация API
-	apiInstance := api.New(dbInstance, errChan)
+	apiInstance := api.New(dbInstance, conf, errChan)
 	if apiInstance == nil {
-		fmt.Println("Failed to initialize API, exiting...")
 		select {
 		case err := <-errChan:
-			fmt.Printf("Error: %v\n", err)
+			return supervisor.Fatal(fmt.Errorf("failed to initialize API: %w", err))
 		default:
-			fmt.Println("Unknown API initialization error")
+			return supervisor.Fatal(errors.New("unknown API initialization error"))
 		}
-		return
 	}
 
-	// Запуск RSS парсера
-	go func() {
-		if err := rss.Rss(ctx, dbInstance, errChan); err != nil {
-			errChan <- fmt.Errorf("RSS parser stopped: %w", err)
-		}
-	}()
+	// current - последние применённые настройки: с ними поллер стартует
+	// заново, если супервизор его перезапускает
+	var current atomic.Pointer[config.Config]
+	current.Store(conf)
+	// Буфер на одно значение: цикл сигналов не ждёт поллер, а поллер,
+	// занятый или перезапускаемый, получит только последние настройки
+	reload := make(chan *config.Config, 1)
 
-	// Запуск HTTP сервера
+	// Поллер и HTTP-сервер работают под надзором: после временных сбоев они
+	// перезапускаются, а процесс завершается только на неустранимой ошибке
+	sup := supervisor.New(errChan)
+	stopped := make(chan struct{})
 	go func() {
-		fmt.Println("Starting HTTP server on :8000...")
-		if err := http.ListenAndServe(":8000", apiInstance.Router()); err != nil {
-			errChan <- fmt.Errorf("HTTP server error: %w", err)
-		}
+		defer close(stopped)
+		sup.Run(ctx,
+			supervisor.Service{Name: "RSS parser", Run: func(ctx context.Context) error {
+				return rss.Rss(ctx, dbInstance, current.Load(), reload, errChan)
+			}},
+			supervisor.Service{Name: "HTTP server", Run: func(ctx context.Context) error {
+				fmt.Printf("Starting HTTP server on %s...\n", conf.Listen)
+				return apiInstance.Serve(ctx)
+			}},
+		)
 	}()
 
-	// Обработка сигналов и ошибок
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// Обработка сигналов и ошибок. SIGHUP и изменение файла настроек
+	// перечитывают настройки без остановки сервера
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	var fileChanged <-chan struct{}
+	if conf.Path != "" {
+		fileChanged = config.Watch(ctx, conf.Path, watchInterval)
+	}
+	reloadConfig := func(reason string) {
+		next, err := config.Load(args)
+		if err != nil {
+			fmt.Printf("Config reload (%s) failed, keeping current settings: %v\n", reason, err)
+			return
+		}
+		diff := current.Load().Diff(next)
+		if len(diff) == 0 {
+			fmt.Printf("Config reloaded (%s): no changes\n", reason)
+			return
+		}
+		fmt.Printf("Config reloaded (%s):\n", reason)
+		for _, line := range diff {
+			fmt.Printf("  %s\n", line)
+		}
+		current.Store(next)
+		offer(reload, next)
+	}
 
-	for {
+	var fatal error
+	for running := true; running; {
 		select {
 		case err := <-errChan:
-			fmt.Printf("Fatal error: %v\n", err)
-			cancel()
-			return
-		case sig := <-sigChan:
+			if !supervisor.IsFatal(err) {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			fatal = err
+			running = false
+		case sig := <-stop:
 			fmt.Printf("Received signal: %v, shutting down...\n", sig)
-			cancel()
-			return
+			running = false
+		case <-hupChan:
+			reloadConfig("SIGHUP")
+		case <-fileChanged:
+			reloadConfig(conf.Path + " changed")
+		}
+	}
+
+	// Даём серверу ответить на начатые запросы, а поллеру - сохранить
+	// загруженные записи. Подсистемы сами укладываются в shutdown,
+	// небольшой запас нужен на последнюю вставку.
+	cancel()
+	select {
+	case <-stopped:
+		fmt.Println("Shutdown complete")
+	case <-time.After(shutdown + time.Second):
+		fmt.Println("Shutdown timeout exceeded, exiting")
+	}
+	return fatal
+}
+
+// offer передаёт настройки в reload без ожидания, заменяя ещё не
+// прочитанные. Отправитель у reload один, поэтому после вычитывания
+// место в буфере гарантированно есть.
+func offer(reload chan *config.Config, next *config.Config) {
+	select {
+	case <-reload:
+	default:
+	}
+	reload <- next
+}
+
+// openStore открывает хранилище, выбранное в настройках.
+func openStore(ctx context.Context, conf config.Database, errChan chan error) (db.Store, error) {
+	if conf.Driver == config.DriverSQLite {
+		return sqlite.New(ctx, conf.Path)
+	}
+	store := db.New(ctx, conf, errChan)
+	if store == nil {
+		select {
+		case err := <-errChan:
+			return nil, err
+		default:
+			return nil, errors.New("unknown database initialization error")
 		}
 	}
+	return store, nil
 }
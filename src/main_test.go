@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goNews/pkg/config"
+	"goNews/pkg/supervisor"
+)
+
+// TestServeFatal проверяет, что сервер завершается на неустранимой ошибке,
+// а не перезапускает подсистему до бесконечности
+func TestServeFatal(t *testing.T) {
+	// Занятый адрес: сервер не сможет его слушать
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"rss": []}`), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	base := []string{"-config", path, "-db-driver", "sqlite", "-db-path", filepath.Join(dir, "news.db"),
+		"-webapp-dir", dir, "-shutdown-timeout", "1"}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "Address in use",
+			args: append(base[:len(base):len(base)], "-listen", ln.Addr().String()),
+		},
+		{
+			name: "Invalid config",
+			args: append(base[:len(base):len(base)], "-concurrency", "-1"),
+		},
+		{
+			name: "Unreadable config file",
+			args: []string{"-config", filepath.Join(dir, "missing.json")},
+		},
+		{
+			name: "Unknown command",
+			args: append(base[:len(base):len(base)], "-listen", "127.0.0.1:0", "serve"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() { done <- serve(tt.args, make(chan os.Signal)) }()
+			select {
+			case err := <-done:
+				if !supervisor.IsFatal(err) {
+					t.Errorf("serve() error = %v, expected a fatal error", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("serve() did not exit on a fatal error")
+			}
+		})
+	}
+}
+
+// TestOffer проверяет, что передача настроек не блокируется без читателя
+// и поллер получает только последние
+func TestOffer(t *testing.T) {
+	reload := make(chan *config.Config, 1)
+	first, last := &config.Config{Listen: "first"}, &config.Config{Listen: "last"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		offer(reload, first)
+		offer(reload, last)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("offer() blocked without a reader")
+	}
+
+	if got := <-reload; got != last {
+		t.Errorf("Received %q, expected %q", got.Listen, last.Listen)
+	}
+	select {
+	case got := <-reload:
+		t.Errorf("Unexpected stale settings %q", got.Listen)
+	default:
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goNews/pkg/config"
+	"goNews/pkg/db"
+	"goNews/pkg/db/sqlite"
+	"strconv"
+)
+
+// runMigrate выполняет подкоманду migrate [флаги] [up | down [N] | status]
+// и возвращает код выхода.
+func runMigrate(args []string) int {
+	conf, err := config.Load(args)
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		return 2
+	}
+	command, rest := "up", conf.Args
+	if len(rest) > 0 {
+		command, rest = rest[0], rest[1:]
+	}
+
+	ctx := context.Background()
+	if conf.Database.Driver == config.DriverSQLite {
+		err = migrateSQLite(ctx, conf.Database.Path, command)
+	} else {
+		err = migratePostgres(ctx, conf.Database, command, rest)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func migratePostgres(ctx context.Context, conf config.Database, command string, args []string) error {
+	steps := 1
+	switch {
+	case command == "down" && len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of migrations to roll back: %s", args[0])
+		}
+		steps = n
+	case len(args) > 0:
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+
+	store, err := db.Connect(ctx, conf)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch command {
+	case "up":
+		return store.Migrate(ctx)
+	case "down":
+		return store.MigrateDown(ctx, steps)
+	case "status":
+		migrations, err := store.Migrations(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			applied := "pending"
+			if m.AppliedAt != nil {
+				applied = "applied " + m.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, applied)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+}
+
+// migrateSQLite: схема файла SQLite доводится до текущей при открытии,
+// откат не поддерживается.
+func migrateSQLite(ctx context.Context, path, command string) error {
+	if command != "up" && command != "status" {
+		return errors.New("sqlite driver supports only migrate up and status")
+	}
+	store, err := sqlite.New(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	current, latest, err := store.Version(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: schema version %d of %d\n", path, current, latest)
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"goNews/pkg/config"
+	"goNews/pkg/db"
+	"goNews/pkg/opml"
+	"io"
+	"os"
+	"time"
+)
+
+// runOPML выполняет подкоманду opml [флаги] (import ФАЙЛ | export [ФАЙЛ])
+// и возвращает код выхода. Файл «-» - стандартный ввод или вывод.
+func runOPML(args []string) int {
+	conf, err := config.Load(args)
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		return 2
+	}
+	command, path := "", "-"
+	if len(conf.Args) > 0 {
+		command = conf.Args[0]
+	}
+	if len(conf.Args) == 2 {
+		path = conf.Args[1]
+	}
+	if !(command == "import" && len(conf.Args) == 2) && !(command == "export" && len(conf.Args) <= 2) {
+		fmt.Println("Usage: goNews opml [flags] import FILE | export [FILE]")
+		return 2
+	}
+
+	ctx := context.Background()
+	store, err := openStore(ctx, conf.Database, make(chan error, 10))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	if command == "import" {
+		err = importOPML(ctx, store, path)
+	} else {
+		err = exportOPML(ctx, store, path)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func importOPML(ctx context.Context, store db.Store, path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	subs, err := opml.Parse(r)
+	if err != nil {
+		return err
+	}
+	result, err := opml.Import(ctx, store, subs)
+	if err != nil {
+		return err
+	}
+	for _, msg := range result.Errors {
+		fmt.Printf("Skipped %s\n", msg)
+	}
+	fmt.Printf("Imported %d feeds, %d already present\n", len(result.Added), len(result.Existing))
+	return nil
+}
+
+func exportOPML(ctx context.Context, store db.Store, path string) error {
+	subs, err := opml.Export(ctx, store)
+	if err != nil {
+		return err
+	}
+	doc := opml.New("goNews", subs, time.Now())
+	if path == "-" {
+		return doc.Write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := doc.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}